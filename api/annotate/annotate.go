@@ -0,0 +1,231 @@
+// registry of the annotations yew source may use, e.g., `[@inline]` and `--@infixr 0 ($)`
+//
+// each annotation declares the arguments it takes and the nodes it may target. Annotations are
+// validated against the `Default` registry, which Go code can extend with `Register`
+package annotate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// kinds of nodes an annotation may target
+type Target uint16
+
+const (
+	// the meta section, i.e., annotations preceding the module declaration
+	OnModule Target = 1 << iota
+	OnImport
+	OnDef
+	OnTyping
+	OnTypeDef
+	OnConstructor
+	OnAlias
+	OnSpec
+	OnInst
+	OnSyntax
+	OnFooter
+)
+
+const (
+	// any body element
+	OnElement = OnDef | OnTyping | OnTypeDef | OnAlias | OnSpec | OnInst | OnSyntax
+	// any node that can be annotated
+	Anywhere = OnModule | OnImport | OnElement | OnConstructor | OnFooter
+)
+
+var targetStrings = []string{
+	"modules",
+	"imports",
+	"definitions",
+	"typings",
+	"type definitions",
+	"constructors",
+	"type aliases",
+	"specs",
+	"instances",
+	"syntax rules",
+	"footers",
+}
+
+// lists the targets in `t`, e.g., "definitions or typings"
+func (t Target) String() string {
+	var names []string
+	for i, name := range targetStrings {
+		if t&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	switch len(names) {
+	case 0:
+		return "nothing"
+	case 1:
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// kinds of annotation arguments
+type Kind byte
+
+const (
+	// a non-negative integer, e.g., `6`
+	Int Kind = iota
+	// a string, e.g., `"use g instead"`
+	String
+	// an identifier, e.g., `Symbols`
+	Name
+	// a parenthesized operator, e.g., `(+)`
+	Operator
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Int:
+		return "integer"
+	case String:
+		return "string"
+	case Name:
+		return "name"
+	case Operator:
+		return "operator"
+	}
+	return "unknown"
+}
+
+// returns the kind prefixed by its indefinite article, e.g., "an integer"
+func (k Kind) article() string {
+	if k == Int || k == Operator {
+		return "an " + k.String()
+	}
+	return "a " + k.String()
+}
+
+// an annotation argument
+type Arg struct {
+	Kind
+	// value of the argument without any quotes or parentheses, e.g., `+` for `(+)`
+	Value string
+}
+
+// declares an annotation, its parameters, and the nodes it may target
+type Spec struct {
+	Name    string
+	Targets Target
+	// kinds of the arguments the annotation takes, in order
+	Params []Kind
+	// number of trailing parameters that may be omitted
+	Optional int
+	// true iff the last parameter may be repeated
+	Variadic bool
+}
+
+// returns an error describing how `args` fail to match the parameters of `spec`, or nil if they
+// match
+func (spec Spec) Check(args []Arg) error {
+	required := len(spec.Params) - spec.Optional
+	if len(args) < required {
+		return fmt.Errorf("`%s` takes at least %d argument(s), got %d", spec.Name, required, len(args))
+	}
+	if !spec.Variadic && len(args) > len(spec.Params) {
+		return fmt.Errorf("`%s` takes at most %d argument(s), got %d", spec.Name, len(spec.Params), len(args))
+	}
+
+	for i, arg := range args {
+		param := spec.Params[min(i, len(spec.Params)-1)]
+		if arg.Kind != param {
+			return fmt.Errorf("argument %d of `%s` must be %s, got %s", i+1, spec.Name, param.article(), arg.Kind.article())
+		}
+	}
+	return nil
+}
+
+// annotations by name
+//
+// a registry is safe for concurrent use, though annotations are usually registered once, e.g., from
+// an `init` function, before any source is parsed
+type Registry struct {
+	mu    sync.RWMutex
+	specs map[string]Spec
+}
+
+// returns a registry of the builtin annotations
+func NewRegistry() *Registry {
+	r := &Registry{specs: make(map[string]Spec, len(builtins))}
+	for _, spec := range builtins {
+		r.specs[spec.Name] = spec
+	}
+	return r
+}
+
+// adds `spec` to the registry, failing if the spec is malformed or an annotation of the same name
+// is already registered
+func (r *Registry) Register(spec Spec) error {
+	if spec.Name == "" {
+		return errors.New("annotation must be named")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, found := r.specs[spec.Name]; found {
+		return fmt.Errorf("annotation `%s` is already registered", spec.Name)
+	}
+	if spec.Optional < 0 || spec.Optional > len(spec.Params) {
+		return fmt.Errorf("annotation `%s` has %d optional parameter(s), but only %d parameter(s)", spec.Name, spec.Optional, len(spec.Params))
+	}
+	if spec.Variadic && len(spec.Params) == 0 {
+		return fmt.Errorf("variadic annotation `%s` must have a parameter", spec.Name)
+	}
+	r.specs[spec.Name] = spec
+	return nil
+}
+
+// removes the annotation named `name` from the registry, returning true iff it was registered
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, found := r.specs[name]
+	delete(r.specs, name)
+	return found
+}
+
+// returns the annotation named `name`, if one is registered
+func (r *Registry) Lookup(name string) (spec Spec, found bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	spec, found = r.specs[name]
+	return spec, found
+}
+
+// annotations every registry starts with
+var builtins = []Spec{
+	// e.g., `[@deprecated "use g instead"]`
+	{Name: "deprecated", Targets: OnElement | OnConstructor, Params: []Kind{String}, Optional: 1},
+	// e.g., `[@infix 4 (==) (/=)]`
+	{Name: "infix", Targets: Anywhere, Params: []Kind{Int, Operator}, Variadic: true},
+	// e.g., `[@infixl 6 (+) (-)]`
+	{Name: "infixl", Targets: Anywhere, Params: []Kind{Int, Operator}, Variadic: true},
+	// e.g., `[@infixr 0 ($)]`
+	{Name: "infixr", Targets: Anywhere, Params: []Kind{Int, Operator}, Variadic: true},
+	// e.g., `[@inline]`
+	{Name: "inline", Targets: OnDef | OnTyping},
+	// e.g., `--@log Symbols`
+	{Name: "log", Targets: OnImport, Params: []Kind{Name}, Variadic: true},
+}
+
+// registry annotations are validated against
+var Default = NewRegistry()
+
+// adds `spec` to the default registry
+//
+// SEE: `Registry.Register`
+func Register(spec Spec) error { return Default.Register(spec) }
+
+// removes the annotation named `name` from the default registry
+//
+// SEE: `Registry.Unregister`
+func Unregister(name string) bool { return Default.Unregister(name) }
+
+// returns the annotation named `name` from the default registry
+func Lookup(name string) (Spec, bool) { return Default.Lookup(name) }
@@ -0,0 +1,73 @@
+package annotate
+
+import "testing"
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name string
+		spec Spec
+		ok   bool
+	}{
+		{"new", Spec{Name: "memo", Targets: OnDef}, true},
+		{"builtin", Spec{Name: "inline", Targets: OnDef}, false},
+		{"unnamed", Spec{Targets: OnDef}, false},
+		{"too many optional", Spec{Name: "memo", Targets: OnDef, Params: []Kind{Int}, Optional: 2}, false},
+		{"variadic without parameters", Spec{Name: "memo", Targets: OnDef, Variadic: true}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := NewRegistry().Register(test.spec)
+			if (err == nil) != test.ok {
+				t.Fatalf("expected ok == %t, got error %v", test.ok, err)
+			}
+		})
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(Spec{Name: "memo", Targets: OnDef}); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Unregister("memo") {
+		t.Fatal("expected `memo` to be unregistered")
+	}
+	if _, found := r.Lookup("memo"); found {
+		t.Fatal("expected `memo` to no longer be registered")
+	}
+	if r.Unregister("memo") {
+		t.Fatal("expected nothing to unregister")
+	}
+	if err := r.Register(Spec{Name: "memo", Targets: OnDef}); err != nil {
+		t.Fatalf("expected `memo` to be registered again, got error %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	infixl, _ := NewRegistry().Lookup("infixl")
+	deprecated, _ := NewRegistry().Lookup("deprecated")
+	tests := []struct {
+		name string
+		spec Spec
+		args []Arg
+		ok   bool
+	}{
+		{"variadic", infixl, []Arg{{Int, "6"}, {Operator, "+"}, {Operator, "-"}}, true},
+		{"missing", infixl, []Arg{{Int, "6"}}, false},
+		{"wrong kind", infixl, []Arg{{Operator, "+"}, {Operator, "-"}}, false},
+		{"wrong repeated kind", infixl, []Arg{{Int, "6"}, {Operator, "+"}, {Int, "7"}}, false},
+		{"optional omitted", deprecated, nil, true},
+		{"optional given", deprecated, []Arg{{String, "use g"}}, true},
+		{"too many", deprecated, []Arg{{String, "use g"}, {String, "now"}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.spec.Check(test.args)
+			if (err == nil) != test.ok {
+				t.Fatalf("expected ok == %t, got error %v", test.ok, err)
+			}
+		})
+	}
+}
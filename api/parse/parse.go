@@ -0,0 +1,69 @@
+// public entry point for parsing yew source code
+package parse
+
+import (
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/api/util"
+	"github.com/petersalex27/yew/internal/errors"
+	"github.com/petersalex27/yew/internal/lexer"
+	"github.com/petersalex27/yew/internal/parser"
+)
+
+// result of parsing a yew source
+type Result struct {
+	// root of the AST, nil if parsing failed
+	Root api.SourceRoot
+	// all errors encountered while lexing and parsing
+	Errors []error
+	// all warnings encountered while lexing and parsing
+	Warnings []error
+}
+
+// returns true iff the source was parsed without error
+func (r Result) Ok() bool { return r.Root != nil && len(r.Errors) == 0 }
+
+// lexes and parses `src`
+//
+// the AST is returned even if the source isn't well-formed in other ways, e.g., if it's ill-typed;
+// SEE: `Check`
+func Source(src api.Source) Result {
+	p := parser.Init(lexer.Init(src))
+	res := Result{}
+	if root, ok := parser.Run(p).(api.SourceRoot); ok {
+		res.Root = root
+	}
+	res.Errors, res.Warnings = p.Errors(), p.Warnings()
+	return res
+}
+
+// reads, lexes, and parses the file at `path`
+func File(path string) Result {
+	src, err := util.FileSource(path)
+	if err != nil {
+		return Result{Errors: []error{errors.OS(err.Error())}, Warnings: []error{}}
+	}
+	return Source(src)
+}
+
+// lexes, parses, expands syntax in, derives instances for, analyzes, and type checks `src`,
+// returning the diagnostics of every pass that ran; the root is nil unless every pass succeeded
+func Check(src api.Source) Result {
+	p := parser.Init(lexer.Init(src))
+	res := Result{}
+	parser.Run(p)
+	parser.Expand(p)
+	if root, ok := parser.Derive(p).(api.SourceRoot); ok && parser.Analyze(p) && parser.Check(p) {
+		res.Root = root
+	}
+	res.Errors, res.Warnings = p.Errors(), p.Warnings()
+	return res
+}
+
+// reads the file at `path`, then checks it like `Check`
+func CheckFile(path string) Result {
+	src, err := util.FileSource(path)
+	if err != nil {
+		return Result{Errors: []error{errors.OS(err.Error())}, Warnings: []error{}}
+	}
+	return Check(src)
+}
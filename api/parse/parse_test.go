@@ -0,0 +1,90 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/petersalex27/yew/api/parse"
+	"github.com/petersalex27/yew/api/util"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		ok     bool
+	}{
+		{"empty", "", true},
+		{"module", "module main", true},
+		{"def", "module main\n\nx = y", true},
+		{"unclosed", "module main\n\nx = (", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := parse.Source(util.StringSource(test.source))
+			if res.Ok() != test.ok {
+				t.Fatalf("expected Ok() == %t, got %t (errors: %v)", test.ok, res.Ok(), res.Errors)
+			}
+			if !test.ok {
+				if len(res.Errors) == 0 {
+					t.Fatalf("expected errors")
+				}
+				return
+			}
+			if res.Root.Header() == nil || res.Root.Body() == nil || res.Root.Footer() == nil {
+				t.Fatalf("expected non-nil sections")
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// substring of the first error, if any
+		message string
+	}{
+		{"def", "module main\n\nx = x", ""},
+		{"unbound", "module main\n\nx = y", "name is not bound: y"},
+		{"ill-typed", "module main\n\nUnit : Type where U : Unit\n\nu : Unit\nu = Unit", "Error (Type)"},
+		{"unclosed", "module main\n\nx = (", "Error (Syntax)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := parse.Check(util.StringSource(test.source))
+			if test.message == "" {
+				if !res.Ok() {
+					t.Fatalf("unexpected failure: %v", res.Errors)
+				}
+				return
+			}
+			if res.Ok() || len(res.Errors) == 0 || !strings.Contains(res.Errors[0].Error(), test.message) {
+				t.Errorf("expected an error containing %q, got %v", test.message, res.Errors)
+			}
+			// the source is still parsed
+			if parsed := parse.Source(util.StringSource(test.source)); test.name != "unclosed" && !parsed.Ok() {
+				t.Errorf("expected the source to parse, got %v", parsed.Errors)
+			}
+		})
+	}
+}
+
+func TestLiterateSource(t *testing.T) {
+	document := "# Main\n\nA module is declared first.\n\n> module main\n\nThen a definition:\n\n```yew\nx = y\n```\n"
+	res := parse.Check(util.FreeSource("main.lyew", document))
+	if len(res.Errors) != 1 {
+		t.Fatalf("expected one error, got %v", res.Errors)
+	}
+	// the error points into the document
+	if msg := res.Errors[0].Error(); !strings.Contains(msg, "[10:") || !strings.Contains(msg, "10 | x = y") {
+		t.Errorf("expected the error to point to line 10 of the document, got %q", msg)
+	}
+
+	res = parse.Check(util.FreeSource("main.lyew", strings.Replace(document, "x = y", "x = x", 1)))
+	if !res.Ok() {
+		t.Errorf("unexpected failure: %v", res.Errors)
+	}
+}
@@ -16,3 +16,14 @@ type Parser interface {
 	// this should return a new instance of the parser with all fields reset to their initial state
 	Clear() Parser
 }
+
+// root of a parsed source file
+type SourceRoot interface {
+	DescribableNode
+	// returns the header section, i.e., the module declaration and imports
+	Header() Node
+	// returns the body section, i.e., all top-level declarations and definitions
+	Body() Node
+	// returns the footer section, i.e., the trailing annotations
+	Footer() Node
+}
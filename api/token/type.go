@@ -296,6 +296,20 @@ func (ty Type) CommandString() string {
 		return "Command(Run)"
 	case Set_c:
 		return "Command(Set)"
+	case Kind_c:
+		return "Command(Kind)"
+	case Api_c:
+		return "Command(Api)"
+	case Save_c:
+		return "Command(Save)"
+	case Restore_c:
+		return "Command(Restore)"
+	case Begin_c:
+		return "Command(Begin)"
+	case End_c:
+		return "Command(End)"
+	case Include_c:
+		return "Command(Include)"
 	default:
 		return fmt.Sprintf("Command(%d)", ty)
 	}
@@ -390,6 +404,13 @@ var commandStringMap = map[Type]string{
 	Quit_c:      ":quit",
 	Run_c:       ":run",
 	Set_c:       ":set",
+	Kind_c:      ":kind",
+	Api_c:       ":api",
+	Save_c:      ":save",
+	Restore_c:   ":restore",
+	Begin_c:     ":begin",
+	End_c:       ":end",
+	Include_c:   ":include",
 }
 
 // return the standard form of a command literal string for a given command type
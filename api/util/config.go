@@ -2,6 +2,8 @@ package util
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/petersalex27/yew/api"
@@ -22,8 +24,9 @@ func ExposeConfig(cfg api.Config) string {
 		return "Config{}"
 	}
 	b := &strings.Builder{}
-	for key, value := range all {
-		b.WriteString(key + ": " + fmt.Sprint(value) + ", ")
+	// keys are sorted so the result doesn't depend on map order
+	for _, key := range slices.Sorted(maps.Keys(all)) {
+		b.WriteString(key + ": " + fmt.Sprint(all[key]) + ", ")
 	}
 	res := b.String()
 	// remove trailing ", "
@@ -8,6 +8,7 @@ import (
 	"github.com/petersalex27/yew/common"
 )
 
+// returns the line and column (both starting at 1) of the byte position `pos` in `source`
 func CalcLocation(source api.SourceCode, pos int, isEndPos bool) (line, char int) {
 	endPositions := source.EndPositions()
 	if len(endPositions) == 0 {
@@ -16,7 +17,11 @@ func CalcLocation(source api.SourceCode, pos int, isEndPos bool) (line, char int
 
 	line = 1 + common.SearchRange(endPositions, pos, isEndPos) // 1 + result = 0 or greater
 	if line > 0 {
-		char = (endPositions[line-1] + 1) - pos
+		lineStart := 0
+		if line > 1 {
+			lineStart = endPositions[line-2]
+		}
+		char = pos - lineStart + 1
 	}
 	return line, char
 }
@@ -121,3 +121,27 @@ func TestWindow(t *testing.T) {
 		})
 	}
 }
+
+func TestCalcLocation(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		pos        int
+		line, char int
+	}{
+		{"first line", "line 1\nline 2\n", 2, 1, 3},
+		{"line start", "line 1\nline 2\n", 7, 2, 1},
+		{"later line", "line 1\nline 2\n", 12, 2, 6},
+		// positions are byte offsets, so `λ` counts as two
+		{"non-ASCII", "-- λ\nx = y\n", 10, 2, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcCode := (source.SourceCode{}).Set(mockSource{path: "/path/to/source", content: tt.content})
+			if line, char := util.CalcLocation(srcCode, tt.pos, false); line != tt.line || char != tt.char {
+				t.Errorf("expected %d:%d, got %d:%d", tt.line, tt.char, line, char)
+			}
+		})
+	}
+}
@@ -4,36 +4,93 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/petersalex27/yew/api/parse"
 	"github.com/petersalex27/yew/cmd/yew/repl"
 )
 
 var (
 	interactive = flag.Bool("i", false, "-i")
 	file        = flag.String("file", "", "-file <path>")
+	args        = flag.String("args", "", "-args <path or YAML>, arguments of the REPL")
 )
 
 func init() {
 	flag.Usage = func() {
 		// TODO
-		fmt.Fprintf(os.Stderr, "usage: yew [-i] [-file <path>]\n")
+		fmt.Fprintf(os.Stderr, "usage: yew [-i] [-args <path or YAML>] [-file <path>]\n       yew repl [-args <path or YAML>] [-i <path1,path2,..>] [-o <path>] [-L]\n")
 		flag.PrintDefaults()
 	}
 }
 
-func compileFile(path string) {
-	// lex := lexer.Init(source.FilePath(path))
-	// lex.Write()
-	// /*tokens*/ _, ok := lex.Tokenize()
-	// if !ok {
-	// 	msgs := lex.FlushMessages()
-	// 	errors.PrintErrors(msgs)
+// prints each error to stderr
+func printErrors(es []error) {
+	for _, e := range es {
+		fmt.Fprintf(os.Stderr, "%v\n", e)
+	}
+}
 
-	// 	os.Exit(1)
-	// }
+// checks the file at `path`, reporting all warnings and errors to stderr
+//
+// returns the exit code: 0 if the file was compiled without error, 1 otherwise
+func compileFile(path string) int {
+	res := parse.CheckFile(path)
+	printErrors(res.Warnings)
+	if !res.Ok() {
+		printErrors(res.Errors)
+		return 1
+	}
+	return 0
+}
+
+// starts the REPL with the arguments read from `pathOrYaml`, if any, also importing each module file
+// of `imports`, recording its definitions to `output` if it's not empty, and reading literate input
+// if `literate` is true
+//
+// returns the exit code: 0 if the REPL was started, 1 if its arguments couldn't be read
+func startRepl(pathOrYaml string, imports []string, output string, literate bool) int {
+	replArgs := repl.DefaultArgs()
+	if pathOrYaml != "" {
+		var err error
+		if replArgs, err = repl.ReadArgs(pathOrYaml); err != nil {
+			fmt.Fprintf(os.Stderr, "bad REPL arguments: %v\n", err)
+			return 1
+		}
+	}
+	replArgs.Import = append(replArgs.Import, imports...)
+	if output != "" {
+		replArgs.Output = output
+	}
+	if literate && replArgs.Literate == nil {
+		replArgs.Literate = new(string)
+	}
+	repl.Run(replArgs)
+	return 0
+}
 
-	// p := util.InitParser[*parser.Parser](lex.SourceCode)
-	// _, _ = p.Begin() // TODO
+// parses the flags following `yew repl`
+func replCommand(arguments []string) int {
+	flags := flag.NewFlagSet("repl", flag.ExitOnError)
+	args := flags.String("args", "", "-args <path or YAML>, arguments of the REPL")
+	var imports string
+	for _, name := range []string{"i", "import"} {
+		flags.StringVar(&imports, name, "", "-"+name+" <path1,path2,..>, module files the REPL imports")
+	}
+	var output string
+	for _, name := range []string{"o", "out", "output"} {
+		flags.StringVar(&output, name, "", "-"+name+" <path>, file the REPL's definitions are recorded to")
+	}
+	var literate bool
+	for _, name := range []string{"L", "lit", "literate"} {
+		flags.BoolVar(&literate, name, false, "-"+name+", reads input as literate Yew")
+	}
+	flags.Parse(arguments)
+	var paths []string
+	if imports != "" {
+		paths = strings.Split(imports, ",")
+	}
+	return startRepl(*args, paths, output, literate)
 }
 
 func main() {
@@ -42,12 +99,12 @@ func main() {
 	// }
 
 	flag.Parse()
-	fmt.Printf("%t, %s\n", *interactive, *file)
-	if *interactive {
-		repl.Run()
+	if flag.Arg(0) == "repl" {
+		os.Exit(replCommand(flag.Args()[1:]))
+	} else if *interactive || *args != "" {
+		os.Exit(startRepl(*args, nil, "", false))
 	} else if *file != "" {
-		compileFile(*file)
-		os.Exit(0)
+		os.Exit(compileFile(*file))
 	} else {
 		flag.Usage()
 	}
@@ -1,29 +1,58 @@
 package repl
 
-import "gopkg.in/yaml.v3"
+import (
+	"io"
+	"os"
+	"strings"
 
+	"gopkg.in/yaml.v3"
+)
+
+// arguments of a session, given as YAML, e.g.,
+//
+//	import: [base/bool]
+//	ignore-comments: false
 type Args struct {
 	Import []string `yaml:"import"`
-	// nil if not literate, otherwise empty string for no output and a string for output file
+	// nil if not literate, otherwise empty string for no output and a string for output file, which
+	// the session's accepted definitions are recorded to as a literate document
 	Literate *string `yaml:"literate"`
-	Output string `yaml:"output"`
-	IgnoreComments bool `yaml:"ignore-comments"`
+	// file the session's accepted definitions are recorded to, empty if they aren't recorded
+	Output         string `yaml:"output"`
+	IgnoreComments bool   `yaml:"ignore-comments"`
 }
 
 func makeDefault() Args {
 	return Args{
-		Import: []string{},
-		Literate: nil,
-		Output: "",
+		Import:         []string{},
+		Literate:       nil,
+		Output:         "",
 		IgnoreComments: true,
 	}
 }
 
+// DefaultArgs returns the arguments of a session started without any
+func DefaultArgs() Args { return makeDefault() }
+
 func ParseArgs(args string) (Args, error) {
 	out := makeDefault()
-	err := yaml.Unmarshal([]byte(args), out)
-	if err != nil {
+	dec := yaml.NewDecoder(strings.NewReader(args))
+	dec.KnownFields(true)
+	if err := dec.Decode(&out); err != nil && err != io.EOF {
 		return makeDefault(), err
 	}
 	return out, nil
-}
\ No newline at end of file
+}
+
+// reads the arguments from the YAML file at `pathOrYaml`, or, if there's no such file and
+// `pathOrYaml` isn't named like one, parses `pathOrYaml` itself as YAML
+func ReadArgs(pathOrYaml string) (Args, error) {
+	content, err := os.ReadFile(pathOrYaml)
+	if err == nil {
+		return ParseArgs(string(content))
+	}
+	if strings.HasSuffix(pathOrYaml, ".yaml") || strings.HasSuffix(pathOrYaml, ".yml") {
+		return makeDefault(), err
+	}
+	return ParseArgs(pathOrYaml)
+}
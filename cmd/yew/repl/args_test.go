@@ -0,0 +1,75 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	literate := "notes.lyew"
+	tests := []struct {
+		name, yaml string
+		expected   Args
+		fails      bool
+	}{
+		{"empty", "", makeDefault(), false},
+		{"every field", "import: [base/bool, reflect]\nliterate: notes.lyew\noutput: record.yew\nignore-comments: false\n", Args{Import: []string{"base/bool", "reflect"}, Literate: &literate, Output: "record.yew"}, false},
+		{"some fields", "output: record.yew", Args{Import: []string{}, Output: "record.yew", IgnoreComments: true}, false},
+		{"unknown field", "colour: red", makeDefault(), true},
+		{"bad value", "ignore-comments: sometimes", makeDefault(), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := ParseArgs(test.yaml)
+			if test.fails != (err != nil) {
+				t.Fatalf("expected failure to be %t, got %v", test.fails, err)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReadArgs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.yaml")
+	if err := os.WriteFile(path, []byte("output: record.yew\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if args, err := ReadArgs(path); err != nil || args.Output != "record.yew" {
+		t.Errorf("expected the file's arguments, got %+v (%v)", args, err)
+	}
+	if args, err := ReadArgs("ignore-comments: false"); err != nil || args.IgnoreComments {
+		t.Errorf("expected the inline arguments, got %+v (%v)", args, err)
+	}
+	if _, err := ReadArgs(filepath.Join(filepath.Dir(path), "none.yaml")); err == nil {
+		t.Errorf("expected a missing file to fail")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	unit := filepath.Join(t.TempDir(), "unit.yew")
+	if err := os.WriteFile(unit, []byte("Unit : Type where (\n  U : Unit\n)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	args, err := ParseArgs("import: [" + strings.TrimSuffix(unit, ".yew") + "]\nignore-comments: false\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, errs := &bytes.Buffer{}, &bytes.Buffer{}
+	s := newSession(strings.NewReader("U\n:expose x -- c\n"), out, errs)
+	s.reportErrors(s.load(args))
+	s.loop()
+
+	expectInOrder(t, "output", out.String(), []string{"included " + strings.TrimSuffix(unit, ".yew"), "yew> U", "type: Comment"})
+	if errs.Len() != 0 {
+		t.Errorf("unexpected errors: %s", errs.String())
+	}
+}
@@ -2,19 +2,316 @@ package repl
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/api/token"
 	"github.com/petersalex27/yew/api/util"
+	"github.com/petersalex27/yew/internal/errors"
+	"github.com/petersalex27/yew/internal/interpreter"
+	"github.com/petersalex27/yew/internal/lexer"
 	"github.com/petersalex27/yew/internal/parser"
+	"github.com/petersalex27/yew/internal/source"
 )
 
-func expose(p *parser.ParserState) []error {
-	tokens, err := util.Tokenize((*p.ReferenceScanner()), nil)
+// a command's usage and what it does, listed by `:help`
+type commandHelp struct {
+	command, usage, description string
+}
+
+// every command, in the order `:help` lists them
+var commandHelps = []commandHelp{
+	{":type", ":type <expr>", "shows the type of an expression (short form `:t`)"},
+	{":kind", ":kind <type>", "shows the kind of a type (short form `:k`)"},
+	{":main", ":main", "evaluates `main` (short form `:m`)"},
+	{":run", ":run <name>", "evaluates the definition `name` (short form `:r`)"},
+	{":expose", ":expose <source>", "shows the tokens of a line of source"},
+	{":import", ":import <path>", "imports a module file like `:include`--there's no package loader yet (short form `:i`)"},
+	{":instances", ":instances <spec>", "lists the instances of a spec (short form `:in`)"},
+	{":api", ":api [<path>]", "shows the public declarations of a module file, or the declarations of the session"},
+	{":set", ":set [<option> <value>]", "sets an option of the session, or shows every option's value"},
+	{":save", ":save [<name>]", "saves a snapshot of the session's definitions and settings"},
+	{":restore", ":restore [<name>]", "restores the named snapshot, or pops and restores the last one"},
+	{":begin", ":begin", "starts a block of lines, e.g., a data type, to be read as one input"},
+	{":end", ":end", "ends a block started by `:begin`, submitting its lines"},
+	{":include", ":include <path>", "adds the definitions of a file (`.yew` or `.lyew` may be left off) to the session"},
+	{":help", ":help [<command>]", "lists the commands or describes one (short form `:h`)"},
+	{":quit", ":quit", "ends the session (short form `:q`)"},
+}
+
+// runs `command` with its argument `arg`, returning any errors it reports
+func (s *session) command(command, arg string) []error {
+	switch command {
+	case ":type":
+		return s.typeOf(arg)
+	case ":kind":
+		return s.kindOf(arg)
+	case ":main":
+		return s.run("main")
+	case ":run":
+		return s.run(arg)
+	case ":expose":
+		return s.expose(arg)
+	case ":import":
+		return s.include(arg)
+	case ":instances":
+		return s.instances(arg)
+	case ":api":
+		return s.api(arg)
+	case ":set":
+		return s.set(arg)
+	case ":save":
+		return s.save(arg)
+	case ":restore":
+		return s.restore(arg)
+	case ":begin":
+		return s.begin(arg)
+	case ":end":
+		return []error{fmt.Errorf("no block to end, see `:help :begin`")}
+	case ":include":
+		return s.include(arg)
+	case ":help":
+		return s.help(arg)
+	case ":quit":
+		s.quit = true
+		return nil
+	}
+	return []error{fmt.Errorf("command `%s` is not implemented", command)}
+}
+
+// responds with the type of the expression `e`
+func (s *session) typeOf(e string) []error {
+	if e == "" {
+		return []error{fmt.Errorf("expected an expression, see `:help :type`")}
+	}
+	ps, es := s.analyze(s.source(), bind(token.Equal, e))
+	if es != nil {
+		return es
+	}
+	ty, ok := parser.Infer(ps, result)
+	if !ok {
+		return ps.Errors()
+	}
+	s.respond(e + " : " + ty)
+	return nil
+}
+
+// responds with the kind of the type `ty`
+func (s *session) kindOf(ty string) []error {
+	if ty == "" {
+		return []error{fmt.Errorf("expected a type, see `:help :kind`")}
+	}
+	ps, es := s.analyze(s.source(), bind(token.Colon, ty))
+	if es != nil {
+		return es
+	}
+	kind, ok := parser.InferKind(ps, result)
+	if !ok {
+		return ps.Errors()
+	}
+	s.respond(ty + " : " + kind)
+	return nil
+}
+
+// evaluates the definition `name` and responds with its value
+func (s *session) run(name string) []error {
+	if name == "" {
+		return []error{fmt.Errorf("expected a name, see `:help :run`")}
+	}
+	ps, es := s.analyze(s.source(), nil)
+	if es != nil {
+		return es
+	}
+	if !parser.Check(ps) {
+		return ps.Errors()
+	}
+	program, ok := parser.Lower(ps)
+	if !ok {
+		return ps.Errors()
+	}
+
+	v, err := interpreter.New(program, s.config.strategy).Run(name)
+	if err != nil {
+		return []error{err}
+	}
+	s.respond(interpreter.Show(v, s.config.printDepth))
+	return nil
+}
+
+// responds with each instance of the spec `spec` and where it's declared
+func (s *session) instances(spec string) []error {
+	if spec == "" {
+		return []error{fmt.Errorf("expected a spec, see `:help :instances`")}
+	}
+	ps, es := s.analyze(s.source(), nil)
+	if es != nil {
+		return es
+	}
+	instances, ok := parser.Instances(ps, spec)
+	if !ok {
+		return ps.Errors()
+	}
+
+	if len(instances) == 0 {
+		s.respond("no instances of `" + spec + "` are in scope")
+	}
+	for _, inst := range instances {
+		s.respond(fmt.Sprintf("%v -- %s:%d:%d", inst, inst.Path, inst.Line, inst.Char))
+	}
+	return nil
+}
+
+// reads the module file at `path`, which may leave off `.yew` (or, for a literate module, `.lyew`)
+//
+// NOTE: modules are read from files; there's no package loader to resolve an import path yet
+func readModule(path string) (api.Source, error) {
+	file, err := util.FileSource(path)
+	for _, ext := range []string{".yew", source.LiterateExtension} {
+		if err == nil || filepath.Ext(path) != "" {
+			break
+		}
+		file, err = util.FileSource(path + ext)
+	}
+	return file, err
+}
+
+// responds with the public declarations of the module file at `path`, or, if `path` is empty, with
+// every declaration of the session
+func (s *session) api(path string) []error {
+	var ps *parser.ParserState
+	var es []error
+	name := "the session"
+	if path == "" {
+		ps, es = s.parse(s.source(), nil)
+	} else {
+		file, err := readModule(path)
+		if err != nil {
+			return []error{errors.OS(err.Error())}
+		}
+		name = "`" + path + "`"
+		if p := parser.Init(lexer.Init(file)); parser.Run(p) == nil {
+			es = p.Errors()
+		} else {
+			ps = p.(*parser.ParserState)
+		}
+	}
+	if es != nil {
+		for i, e := range es {
+			es[i] = fmt.Errorf("%s: %w", name, e)
+		}
+		return es
+	}
+
+	declarations := parser.Surface(ps)
+	if len(declarations) == 0 {
+		s.respond(name + " declares nothing public")
+	}
+	for _, decl := range declarations {
+		s.respond(decl.Signature)
+		for _, member := range decl.Members {
+			s.respond("    " + member)
+		}
+	}
+	return nil
+}
+
+// sets an option from `arg`, written `<option> <value>`, or, if `arg` is empty, responds with the
+// value of every option
+func (s *session) set(arg string) []error {
+	if arg != "" {
+		if err := s.config.Set(arg); err != nil {
+			return []error{err}
+		}
+	}
+	s.respond(util.ExposeConfig(&s.config))
+	return nil
+}
+
+// starts a block, buffering each line read until `:end`
+func (s *session) begin(arg string) []error {
+	if arg != "" {
+		return []error{fmt.Errorf("`:begin` takes no arguments, see `:help :begin`")}
+	}
+	s.block = &strings.Builder{}
+	return nil
+}
+
+// ends the current block, submitting its lines as one input
+func (s *session) end() []error {
+	block := s.block.String()
+	s.block = nil
+	if strings.TrimSpace(block) == "" {
+		return nil
+	}
+	return s.definitionOrExpression(block)
+}
+
+// adds the definitions of the file at `path` to the session, placing them before the session's
+// own definitions so diagnostics are windowed against the file's lines
+func (s *session) include(path string) []error {
+	if path == "" {
+		return []error{fmt.Errorf("expected a path, see `:help :include`")}
+	}
+	src, err := readModule(path)
+	if err != nil {
+		return []error{errors.OS(err.Error())}
+	}
+	// the file is parsed as a module of its own, so its errors are reported against it
+	s.includes = append(s.includes, src)
+	ps, es := s.analyze(s.source(), nil)
+	if es == nil && !parser.Check(ps) {
+		es = ps.Errors()
+	}
+	if es != nil {
+		s.includes = s.includes[:len(s.includes)-1]
+		for i, e := range es {
+			es[i] = fmt.Errorf("%s: %w", path, e)
+		}
+		return es
+	}
+	s.reportWarnings(ps.Warnings())
+
+	s.respond("included " + path)
+	return s.record()
+}
+
+// responds with each token of `src`
+func (s *session) expose(src string) []error {
+	lex := lexer.Init(util.FreeSource("<stdin>", src))
+	lex.SetKeepComments(s.config.keepComments)
+	tokens, err := util.Tokenize(lex, nil)
 	if err != nil {
 		return []error{(*err).Error()}
 	}
 
 	for _, token := range tokens {
-		fmt.Printf("yew> %s\n", util.ExposeToken(token))
+		s.respond(util.ExposeToken(token))
+	}
+	return nil
+}
+
+// responds with the usage and description of `command`, or of every command if `command` is empty
+func (s *session) help(command string) []error {
+	if command == "" {
+		for _, h := range commandHelps {
+			s.respond(fmt.Sprintf("%-24s%s", h.usage, h.description))
+		}
+		return nil
+	}
+
+	command = ":" + strings.TrimPrefix(command, ":")
+	for _, h := range commandHelps {
+		if h.command == command {
+			s.respond(h.usage)
+			s.respond("    " + h.description)
+			if command == ":set" {
+				for _, opt := range options {
+					s.respond(fmt.Sprintf("    %-28s%s", opt.key+" "+opt.values, opt.description))
+				}
+			}
+			return nil
+		}
 	}
-	return nil // TODO: finish
+	return []error{fmt.Errorf("unknown command `%s`, see `:help`", command)}
 }
@@ -0,0 +1,162 @@
+package repl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/internal/interpreter"
+)
+
+// which warnings a session reports
+type warningLevel int
+
+const (
+	// no warnings are reported
+	warnNone warningLevel = iota
+	// each warning is reported the first time it's found
+	warnOnce
+	// each warning is reported every time it's found
+	warnAll
+)
+
+func (level warningLevel) String() string {
+	return [...]string{warnNone: "none", warnOnce: "once", warnAll: "all"}[level]
+}
+
+// options of a session, changed with `:set <option> <value>`
+type config struct {
+	// whether `:expose` shows comments
+	keepComments bool
+	strategy     interpreter.Strategy
+	warnings     warningLevel
+	// how many constructors deep values are printed, 0 if there's no limit
+	printDepth int
+	// whether the type of an evaluated expression is shown with its value
+	showTypes bool
+}
+
+var _ api.Config = (*config)(nil)
+
+func defaultConfig() config {
+	return config{strategy: interpreter.Strict, warnings: warnOnce}
+}
+
+// an option of a session's config
+type option struct {
+	key, values, description string
+	set                      func(cfg *config, value string) error
+	get                      func(cfg *config) any
+}
+
+// every option, in the order `:set` lists them
+var options = []option{
+	{
+		"keep-comments", "true|false", "whether `:expose` shows comments",
+		func(cfg *config, value string) (err error) {
+			cfg.keepComments, err = parseBool(value)
+			return err
+		},
+		func(cfg *config) any { return cfg.keepComments },
+	},
+	{
+		"strategy", "strict|lazy", "when arguments and bindings are evaluated",
+		func(cfg *config, value string) (err error) {
+			cfg.strategy, err = parseChoice(value, interpreter.Strict, interpreter.Lazy)
+			return err
+		},
+		func(cfg *config) any { return cfg.strategy },
+	},
+	{
+		"warnings", "none|once|all", "which warnings are reported",
+		func(cfg *config, value string) (err error) {
+			cfg.warnings, err = parseChoice(value, warnNone, warnOnce, warnAll)
+			return err
+		},
+		func(cfg *config) any { return cfg.warnings },
+	},
+	{
+		"print-depth", "<depth>", "how many constructors deep values are printed, 0 for no limit",
+		func(cfg *config, value string) error {
+			depth, err := strconv.Atoi(value)
+			if err != nil || depth < 0 {
+				return fmt.Errorf("expected a non-negative integer, got `%s`", value)
+			}
+			cfg.printDepth = depth
+			return nil
+		},
+		func(cfg *config) any { return cfg.printDepth },
+	},
+	{
+		"show-types", "true|false", "whether the type of an evaluated expression is shown",
+		func(cfg *config, value string) (err error) {
+			cfg.showTypes, err = parseBool(value)
+			return err
+		},
+		func(cfg *config) any { return cfg.showTypes },
+	},
+}
+
+func findOption(key string) (option, bool) {
+	for _, opt := range options {
+		if opt.key == key {
+			return opt, true
+		}
+	}
+	return option{}, false
+}
+
+func parseBool(value string) (bool, error) {
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected `true` or `false`, got `%s`", value)
+}
+
+// returns the choice written `value`
+func parseChoice[a fmt.Stringer](value string, choices ...a) (a, error) {
+	names := make([]string, len(choices))
+	for i, choice := range choices {
+		if names[i] = choice.String(); names[i] == value {
+			return choice, nil
+		}
+	}
+	var zero a
+	return zero, fmt.Errorf("expected one of `%s`, got `%s`", strings.Join(names, "`, `"), value)
+}
+
+// sets an option from `raw`, written `<option> <value>`
+func (cfg *config) Set(raw string) error {
+	key, value, _ := strings.Cut(strings.TrimSpace(raw), " ")
+	opt, found := findOption(key)
+	if !found {
+		return fmt.Errorf("unknown option `%s`, see `:help :set`", key)
+	}
+	if value = strings.TrimSpace(value); value == "" {
+		return fmt.Errorf("expected a value for `%s` (%s)", key, opt.values)
+	}
+	if err := opt.set(cfg, value); err != nil {
+		return fmt.Errorf("bad value for `%s`: %w", key, err)
+	}
+	return nil
+}
+
+// returns the value of the option `key`, or nil if there's no such option
+func (cfg *config) Get(key string) any {
+	if opt, found := findOption(key); found {
+		return opt.get(cfg)
+	}
+	return nil
+}
+
+func (cfg *config) All() map[string]any {
+	all := make(map[string]any, len(options))
+	for _, opt := range options {
+		all[opt.key] = opt.get(cfg)
+	}
+	return all
+}
@@ -0,0 +1,59 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/petersalex27/yew/api/util"
+	"github.com/petersalex27/yew/internal/interpreter"
+)
+
+func TestConfigSet(t *testing.T) {
+	tests := []struct {
+		raw, key string
+		expected any
+		// substring of the error, if any
+		message string
+	}{
+		{"keep-comments true", "keep-comments", true, ""},
+		{"strategy lazy", "strategy", interpreter.Lazy, ""},
+		{"warnings  all", "warnings", warnAll, ""},
+		{"print-depth 3", "print-depth", 3, ""},
+		{"show-types false", "show-types", false, ""},
+		{"keep-comments yes", "keep-comments", false, "expected `true` or `false`"},
+		{"strategy eager", "strategy", interpreter.Strict, "expected one of `strict`, `lazy`"},
+		{"print-depth -1", "print-depth", 0, "expected a non-negative integer"},
+		{"warnings", "warnings", warnOnce, "expected a value for `warnings`"},
+		{"colour red", "colour", nil, "unknown option `colour`"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.raw, func(t *testing.T) {
+			cfg := defaultConfig()
+			err := cfg.Set(test.raw)
+			if test.message == "" && err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			} else if test.message != "" && (err == nil || !strings.Contains(err.Error(), test.message)) {
+				t.Errorf("expected an error containing %q, got %v", test.message, err)
+			}
+			if actual := cfg.Get(test.key); actual != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestConfigGet(t *testing.T) {
+	cfg := defaultConfig()
+	if depth, found := util.Get[int](&cfg, "print-depth"); !found || depth != 0 {
+		t.Errorf("expected print depth 0, got %v (found: %t)", depth, found)
+	}
+	if _, found := util.Get[bool](&cfg, "print-depth"); found {
+		t.Errorf("expected print depth not to be a bool")
+	}
+
+	expected := "Config{keep-comments: false, print-depth: 0, show-types: false, strategy: strict, warnings: once}"
+	if actual := util.ExposeConfig(&cfg); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
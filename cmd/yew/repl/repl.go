@@ -3,30 +3,83 @@ package repl
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/petersalex27/yew/api"
-	// "github.com/petersalex27/yew/api/util"
-	// "github.com/petersalex27/yew/internal/lexer"
-	// "github.com/petersalex27/yew/internal/parser"
+	"github.com/petersalex27/yew/api/token"
+	"github.com/petersalex27/yew/api/util"
+	"github.com/petersalex27/yew/internal/errors"
+	"github.com/petersalex27/yew/internal/interpreter"
+	"github.com/petersalex27/yew/internal/lexer"
+	"github.com/petersalex27/yew/internal/parser"
+	"github.com/petersalex27/yew/internal/source"
 )
 
-func prompt() { fmt.Print("yew< ") }
+// name the REPL binds the expressions and types it's asked about to
+const result = "result'"
 
-func respond(output *os.File, resp string) { fmt.Fprintf(output, "yew> %s\n", resp) }
+// state of a REPL session
+type session struct {
+	// source of every accepted definition
+	//
+	// each input is lexed and parsed together with the whole of this source rather than
+	// incrementally: the passes following parsing (expansion, deriving, name analysis, and type
+	// checking) are over a whole program, so the session is re-parsed and re-checked for each input
+	defs string
+	// files added by `:include`, in the order they were added; each is parsed as a module of its own
+	// and merged into the session's source by `parse`
+	includes []api.Source
+	in       *bufio.Reader
+	// where responses and errors are written
+	out, errs io.Writer
+	// options changed by `:set`
+	config config
+	// warnings already reported, so a warning about the session's source is reported only once
+	warned map[string]bool
+	// snapshots saved by `:save`, most recent last
+	snapshots []snapshot
+	// lines read since `:begin`, nil outside of a block
+	block *strings.Builder
+	// file the session's definitions are recorded to, empty if they aren't recorded
+	output string
+	// unliterates each line of input other than commands, nil if input isn't literate
+	literate *source.Literate
+	// file the session's definitions are recorded to as a literate document, empty if they aren't
+	literateOutput string
+	quit           bool
+}
 
-func throw(err error) { respond(os.Stderr, err.Error()) }
+func newSession(in io.Reader, out, errs io.Writer) *session {
+	token.SetReplMode(true)
+	return &session{
+		in:     bufio.NewReader(in),
+		out:    out,
+		errs:   errs,
+		config: defaultConfig(),
+		warned: make(map[string]bool),
+	}
+}
 
-func read(input *bufio.Reader) string {
-	text, _ := input.ReadString('\n')
-	return text
+func (s *session) prompt() {
+	if s.block != nil {
+		fmt.Fprint(s.out, "yew| ")
+	} else {
+		fmt.Fprint(s.out, "yew< ")
+	}
 }
 
-func promptRepl(scanner api.ScannerPlus, input *bufio.Reader) {
-	prompt()
-	scanner.AppendSource(read(input))
+func (s *session) respond(resp string) { fmt.Fprintf(s.out, "yew> %s\n", resp) }
+
+func (s *session) throw(err error) { fmt.Fprintf(s.errs, "yew> %s\n", err.Error()) }
+
+// reads a line of input, returning false once the input is exhausted
+func (s *session) read() (string, bool) {
+	text, err := s.in.ReadString('\n')
+	return text, text != "" || err == nil
 }
 
 // return value will be recognized by the regex
@@ -41,15 +94,266 @@ func version() string {
 	return ""
 }
 
-func reportErrors(errors []error) {
+func (s *session) reportErrors(errors []error) {
 	for _, error := range errors {
-		throw(error)
+		s.throw(error)
+	}
+}
+
+// reports each warning the session's warning level allows, i.e., by default, each warning not
+// already reported
+func (s *session) reportWarnings(warnings []error) {
+	if s.config.warnings == warnNone {
+		return
+	}
+	for _, warning := range warnings {
+		if s.config.warnings == warnAll || !s.warned[warning.Error()] {
+			s.warned[warning.Error()] = true
+			s.throw(warning)
+		}
+	}
+}
+
+// applies the arguments the session was started with, including each of its imports, returning any
+// errors found
+func (s *session) load(args Args) (es []error) {
+	s.config.keepComments = !args.IgnoreComments
+	if args.Literate != nil {
+		s.literate, s.literateOutput = new(source.Literate), *args.Literate
+	}
+	// there's no package loader yet, so imports are read as module files, like `:include`
+	for _, path := range args.Import {
+		es = append(es, s.include(path)...)
+	}
+	s.output = args.Output
+	return append(es, s.record()...)
+}
+
+// writes the session's definitions to its output files, so each is always Yew source (or a literate
+// Yew document) that reproduces the session; if a file can't be written, recording to it stops
+//
+// called whenever the session's definitions change, i.e., once it's loaded, then after each
+// accepted definition, `:include`, and `:restore`
+func (s *session) record() (es []error) {
+	content := s.recorded()
+	es = append(es, record(&s.output, content)...)
+	if s.literateOutput != "" {
+		es = append(es, record(&s.literateOutput, source.BirdTrack(content))...)
+	}
+	return es
+}
+
+// returns the definitions of each included file followed by the source of every accepted
+// definition, i.e., source that reproduces the session
+func (s *session) recorded() string {
+	var b strings.Builder
+	for _, file := range s.includes {
+		if path := file.Path(); source.IsLiterate(path) {
+			// so the definitions aren't indented by the bird tracks blanked when unliterating
+			file = util.FreeSource(strings.TrimSuffix(path, source.LiterateExtension), new(source.Literate).Code(file.String()))
+		}
+		if p := parser.Init(lexer.Init(file)); parser.Run(p) != nil {
+			b.WriteString(parser.Definitions(p))
+		}
+	}
+	return b.String() + s.source()
+}
+
+// writes `content` to the file at `*path` unless `*path` is empty; if it can't be written, `*path`
+// is emptied
+func record(path *string, content string) []error {
+	if *path == "" {
+		return nil
+	}
+	if err := os.WriteFile(*path, []byte(content), 0o644); err != nil {
+		*path = ""
+		return []error{errors.OS(err.Error() + ", so the session is no longer recorded to it")}
+	}
+	return nil
+}
+
+// returns the source of every accepted definition
+func (s *session) source() string { return s.defs }
+
+// reads, evaluates, and responds to each line of input until the input is exhausted or the session
+// is quit
+func (s *session) loop() {
+	for !s.quit {
+		s.prompt()
+		line, ok := s.read()
+		if !ok {
+			fmt.Fprintln(s.out)
+			if s.block != nil {
+				s.throw(fmt.Errorf("block was never ended, expected `:end`"))
+			}
+			return
+		}
+		s.line(line)
+	}
+}
+
+// evaluates a line of input: either a command, a definition, or an expression; in literate mode,
+// every line but a command is unliterated first, so prose is ignored and a bird track doesn't indent
+// the code it marks
+func (s *session) line(line string) {
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+	if s.literate != nil && !strings.HasPrefix(strings.TrimSpace(line), ":") {
+		line = s.literate.Code(line)
+	}
+	command, arg := splitCommand(line)
+	trimmed := strings.TrimSpace(line)
+
+	var es []error
+	switch {
+	case s.block != nil: // only `:end` is a command within a block
+		if command == ":end" {
+			es = s.end()
+		} else {
+			s.block.WriteString(line)
+		}
+	case command != "":
+		es = s.command(command, arg)
+	case strings.HasPrefix(trimmed, ":"):
+		es = []error{fmt.Errorf("unknown command `%s`, see `:help`", strings.Fields(trimmed)[0])}
+	case trimmed == "":
+	default:
+		es = s.definitionOrExpression(line)
 	}
+	s.reportErrors(es)
 }
 
-func Run() {
+// returns the command `line` starts with, in its long form (e.g., `:type` for `:t`), and the rest
+// of the line; the command is empty if `line` doesn't start with one
+func splitCommand(line string) (command, arg string) {
+	lex := lexer.Init(util.FreeSource("<stdin>", line))
+	if command = lex.Command(); command == "" {
+		return "", ""
+	}
+	return command, strings.TrimSpace(line[lex.Pos:])
+}
+
+// accepts `input` as a definition if it parses as one, adding it to the session's source,
+// otherwise, evaluates it as an expression
+func (s *session) definitionOrExpression(input string) []error {
+	defs := s.source() + input
+	if p := parser.Init(lexer.Init(util.FreeSource("<stdin>", defs))); parser.Run(p) == nil {
+		ps, es := s.parse(s.source(), bind(token.Equal, input))
+		if es != nil {
+			return p.Errors() // neither a definition nor an expression, so report why it isn't the former
+		}
+		if es := analyzed(ps); es != nil {
+			return es
+		}
+		return s.evaluate(ps)
+	}
+
+	ps, es := s.analyze(defs, nil)
+	if es == nil && !parser.Check(ps) {
+		es = ps.Errors()
+	}
+	if es != nil {
+		return es
+	}
+	s.defs = defs
+	s.reportWarnings(ps.Warnings())
+	return s.record()
+}
+
+// a scanner that scans `prefix` before the tokens of the scanner it wraps
+type prefixed struct {
+	api.ScannerPlus
+	prefix []api.Token
+}
+
+func (p *prefixed) Scan() api.Token {
+	if len(p.prefix) == 0 {
+		return p.ScannerPlus.Scan()
+	}
+	tok := p.prefix[0]
+	p.prefix = p.prefix[1:]
+	return tok
+}
+
+func (p *prefixed) Eof() bool { return len(p.prefix) == 0 && p.ScannerPlus.Eof() }
+
+// returns a scanner over `input` that binds it to `result` with `binding`, i.e., that scans
+// `result' = <input>` for an expression or `result' : <input>` for a type
+//
+// the binding has no width, so the positions of `input`'s tokens are within the line it was read
+// from rather than within the synthetic definition
+func bind(binding token.Type, input string) api.ScannerPlus {
+	prefix := []api.Token{token.Id.MakeValued(result), binding.Make()}
+	return &prefixed{lexer.Init(util.FreeSource("<stdin>", input)), prefix}
+}
+
+// parses `src` together with each included file, then, unless `input` is nil, with the source of
+// `input` (see `bind`), returning the errors reported if any fails to parse
+func (s *session) parse(src string, input api.ScannerPlus) (*parser.ParserState, []error) {
+	p := parser.Init(lexer.Init(util.FreeSource("<stdin>", src)))
+	ps, isState := p.(*parser.ParserState)
+	if !isState || parser.Run(ps) == nil {
+		return nil, p.Errors()
+	}
+	for _, file := range s.includes {
+		if parser.Include(ps, lexer.Init(file)) == nil {
+			return nil, ps.Errors()
+		}
+	}
+	// appended last, so what it binds is checked against everything else
+	if input != nil && parser.Append(ps, input) == nil {
+		return nil, ps.Errors()
+	}
+	return ps, nil
+}
+
+// runs the passes preceding type checking over `src` and `input` (see `parse`), returning the
+// errors reported if any fails
+func (s *session) analyze(src string, input api.ScannerPlus) (*parser.ParserState, []error) {
+	ps, es := s.parse(src, input)
+	if es != nil {
+		return nil, es
+	}
+	return ps, analyzed(ps)
+}
+
+// runs the passes preceding type checking over the parsed source of `ps`, returning the errors
+// reported if any fails
+func analyzed(ps *parser.ParserState) []error {
+	if parser.Expand(ps) == nil || parser.Derive(ps) == nil || !parser.Analyze(ps) {
+		return ps.Errors()
+	}
+	return nil
+}
+
+// type checks and evaluates the expression bound to `result` in the analyzed source of `ps`
+func (s *session) evaluate(ps *parser.ParserState) []error {
+	ty, ok := parser.Infer(ps, result)
+	if !ok {
+		return ps.Errors()
+	}
+	program, ok := parser.Lower(ps)
+	if !ok {
+		return ps.Errors()
+	}
+
+	v, err := interpreter.New(program, s.config.strategy).Run(result)
+	if err != nil {
+		return []error{err}
+	}
+	if s.config.showTypes {
+		s.respond(interpreter.Show(v, s.config.printDepth) + " : " + ty)
+	} else {
+		s.respond(interpreter.Show(v, s.config.printDepth))
+	}
+	return nil
+}
+
+// starts an interactive session over stdin with the arguments `args`
+func Run(args Args) {
 	// print initial message
-	fmt.Printf("Yew (interactive)" + version() + "\nUse ctrl+C to exit\n\n")
+	fmt.Printf("Yew (interactive)" + version() + "\nUse :quit or ctrl+C to exit\n\n")
 
 	// initialize quit signal
 	sigs := make(chan os.Signal, 1)
@@ -58,41 +362,14 @@ func Run() {
 		switch <-sigs {
 		case syscall.SIGINT:
 			fmt.Println("\nctrl+C detected...")
+			os.Exit(0)
 		case syscall.SIGTERM:
 			fmt.Println("\nexiting...")
 			os.Exit(0)
 		}
 	}()
 
-	// input := bufio.NewReader(os.Stdin)
-
-	// prompt()
-	// lex := lexer.Init(util.FreeSource("<stdin>", read(input)))
-	// p := parser.Init(lex)
-	
-	// for {
-	// 	es := []error{}
-	// 	switch lex.Command() {
-	// 	case "":
-	// 		if !p.ReplParse() {
-	// 			es = p.Errors()
-	// 		}
-	// 		// TODO: evaluate ast
-	// 	case ":import", ":type", ":instances", ":main", ":run", ":set":
-	// 		panic("command not implemented")
-	// 	case ":expose":
-	// 		es = command.Expose(lex)
-	// 	case ":help":
-	// 		es = command.Help(lex)
-	// 	case ":quit":
-	// 		lex.Stop()
-	// 		sigs <- syscall.SIGTERM // exit
-	// 	}
-
-	// 	if es != nil {
-	// 		reportErrors(es)
-	// 		lex.Restore()
-	// 	}
-	// 	promptRepl(lex, input)
-	// }
+	s := newSession(os.Stdin, os.Stdout, os.Stderr)
+	s.reportErrors(s.load(args))
+	s.loop()
 }
@@ -0,0 +1,253 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/petersalex27/yew/api/parse"
+	"github.com/petersalex27/yew/internal/source"
+)
+
+func TestSession(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// substrings of the session's output and errors, in order
+		out, errs []string
+	}{
+		{"evaluate", "Unit : Type where U : Unit\nid : a -> a\nid x = x\nid U\n", []string{"yew> U"}, nil},
+		{"type", "Unit : Type where U : Unit\n:type U\n:t U\n", []string{"yew> U : Unit", "yew> U : Unit"}, nil},
+		{"type of untyped", "Unit : Type where U : Unit\nu = U\n:type u\nid x = x\n:type id\nbad = U U\nu = U\n", []string{"yew> u : Unit", "yew> id : a -> a"}, []string{"term cannot be applied to an argument: `Unit`", "Warning: clause is never reached"}},
+		{"kind", "Unit : Type where U : Unit\n:kind Unit\n", []string{"yew> Unit : Type"}, nil},
+		{"main", "Unit : Type where U : Unit\nmain = U\n:main\n", []string{"yew> U"}, nil},
+		{"run", "Unit : Type where U : Unit\nu = U\n:run u\n", []string{"yew> U"}, nil},
+		{"expose", ":expose x = 1\n", []string{`value: "x"`, `value: "="`, `value: "1"`}, nil},
+		{"help", ":help :quit\n", []string{"yew> :quit", "ends the session"}, nil},
+		{"quit", ":quit\n:frobnicate\n", nil, nil},
+		{"unknown command", ":frobnicate\n", nil, []string{"unknown command `:frobnicate`"}},
+		{"unbound", "id : a -> a\nid x = x\nid U\n", nil, []string{"name is not bound: U"}},
+		{"expression diagnostics", "Unit : Type where U : Unit\nid : a -> a\nid x = x\n   id  (U U)\n:type U U\n:kind Unit Unit\n", nil, []string{"[1:8] Error (Type)", "1 |    id  (U U)", "[1:1] Error (Type)", "1 | U U", "[1:1] Error (Type)", "1 | Unit Unit"}},
+		{"save and restore", "Unit : Type where U : Unit\n:save\nu = U\n:restore\n:run u\n", []string{"saved snapshot #1", "restored snapshot #1"}, []string{"name has no definition: u"}},
+		{"named snapshots", "Unit : Type where U : Unit\n:save a\nu = U\n:save b\n:restore a\n:restore b\n:run u\n:restore a\n:restore a\n:run u\n", []string{"restored snapshot `a`", "restored snapshot `b`", "yew> U", "restored snapshot `a`", "restored snapshot `a`"}, []string{"name has no definition: u"}},
+		{"nothing to restore", ":restore\n:restore a\n", nil, []string{"no snapshots are saved", "no snapshot is named `a`"}},
+		{"block", ":begin\nColor : Type where (\n  Red : Color\n  Blue : Color\n)\n:end\nswap : Color -> Color\n:begin\nswap Red = Blue\nswap Blue = Red\n:end\nswap Red\n", []string{"yew| ", "yew> Blue"}, nil},
+		{"unended block", ":begin\nu = U\n", nil, []string{"block was never ended"}},
+		{"end without begin", ":end\n", nil, []string{"no block to end"}},
+		{"instances", "Unit : Type where U : Unit\n:begin\nspec Eq a where (\n  eq : a -> a -> Unit\n)\n:end\n:begin\ninst Eq Unit where (\n  eq x y = U\n)\n:end\n:instances Eq\n:in Eq\n:instances Show\n", []string{"yew> inst Eq Unit -- <stdin>:5:6", "yew> inst Eq Unit -- <stdin>:5:6", "no instances of `Show` are in scope"}, nil},
+		{"api", ":api\nUnit : Type where U : Unit\nu : Unit\nu = U\n:api\n", []string{"the session declares nothing public", "yew> Unit : Type where", "yew>     U : Unit", "yew> u : Unit"}, nil},
+		{"set", ":set\n:set strategy lazy\n:set print-depth two\n", []string{"yew> Config{keep-comments: false, print-depth: 0, show-types: false, strategy: strict, warnings: once}", "strategy: lazy"}, []string{"bad value for `print-depth`"}},
+		{"show types", "Unit : Type where U : Unit\n:set show-types true\nU\n", []string{"yew> U : Unit"}, nil},
+		{"print depth", "Unit : Type where U : Unit\nBox : Type where B : Unit -> Box\nCrate : Type where C : Box -> Unit -> Crate\n:set print-depth 1\nC (B U) U\n", []string{"yew> C .. U"}, nil},
+		{"keep comments", ":expose x -- c\n:set keep-comments true\n:expose x -- c\n", []string{`value: "x"`, `value: "x"`, "type: Comment"}, nil},
+		{"restored settings", ":save\n:set strategy lazy\n:restore\n:set\n", []string{"strategy: lazy", "strategy: strict"}, nil},
+		{"rolled back", "u = U\nUnit : Type where U : Unit\nu = U\n:run u\n", []string{"yew> U"}, []string{"name is not bound: U"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, errs := &bytes.Buffer{}, &bytes.Buffer{}
+			s := newSession(strings.NewReader(test.input), out, errs)
+			s.loop()
+
+			expectInOrder(t, "output", out.String(), test.out)
+			expectInOrder(t, "errors", errs.String(), test.errs)
+			if test.errs == nil && errs.Len() != 0 {
+				t.Errorf("unexpected errors: %s", errs.String())
+			}
+		})
+	}
+}
+
+func TestInclude(t *testing.T) {
+	dir := t.TempDir()
+	good, bad := filepath.Join(dir, "good.yew"), filepath.Join(dir, "bad.yew")
+	if err := os.WriteFile(good, []byte("Unit : Type where (\n  U : Unit\n)\n\nu : Unit\nu = U\n\nspec Eq a where (\n  eq : a -> Unit\n)\n\ninst Eq Unit where (\n  eq x = U\n)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bad, []byte("v : Unit\nv = W\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, errs := &bytes.Buffer{}, &bytes.Buffer{}
+	s := newSession(strings.NewReader(":include "+good+"\n:run u\n:instances Eq\n:include "+bad+"\n:include "+filepath.Join(dir, "none.yew")+"\n"), out, errs)
+	s.loop()
+
+	// the instance is located in the included file
+	expectInOrder(t, "output", out.String(), []string{"included " + good, "yew> U", "yew> inst Eq Unit -- " + good + ":12:6"})
+	// the error is windowed against the included file's second line
+	expectInOrder(t, "errors", errs.String(), []string{bad + ": [2:", "name is not bound: W", "2 | v = W", "Error (OS)"})
+}
+
+func TestIncludeModules(t *testing.T) {
+	dir := t.TempDir()
+	lib, lib2, bad := filepath.Join(dir, "lib.yew"), filepath.Join(dir, "lib2.yew"), filepath.Join(dir, "bad.lyew")
+	record := filepath.Join(dir, "record.yew")
+	files := map[string]string{
+		lib:  "module lib\n\nUnit : Type where (\n  U : Unit\n)\n",
+		lib2: "module lib2\n\nu : Unit\nu = U\n",
+		bad:  "Some prose.\n\n> w = W\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	args := makeDefault()
+	args.Output = record
+
+	out, errs := &bytes.Buffer{}, &bytes.Buffer{}
+	s := newSession(strings.NewReader(":include "+lib+"\n:include "+lib2+"\nv = u\n:run v\n:include "+bad+"\n"), out, errs)
+	s.reportErrors(s.load(args))
+	s.loop()
+
+	expectInOrder(t, "output", out.String(), []string{"included " + lib, "included " + lib2, "yew> U"})
+	// the error is windowed against the literate document's third line
+	expectInOrder(t, "errors", errs.String(), []string{bad + ": [3:", "name is not bound: W", "3 | > w = W"})
+
+	content, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Unit : Type where (\n  U : Unit\n)\nu : Unit\nu = U\nv = u\n"
+	if string(content) != expected {
+		t.Errorf("expected %q, got %q", expected, string(content))
+	}
+	if res := parse.CheckFile(record); !res.Ok() {
+		t.Errorf("expected the record to compile, got %v", res.Errors)
+	}
+}
+
+func TestImport(t *testing.T) {
+	unit := filepath.Join(t.TempDir(), "unit.yew")
+	if err := os.WriteFile(unit, []byte("Unit : Type where (\n  U : Unit\n)\n\nu : Unit\nu = U\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, errs := &bytes.Buffer{}, &bytes.Buffer{}
+	s := newSession(strings.NewReader(":i "+strings.TrimSuffix(unit, ".yew")+"\n:run u\n:import\n"), out, errs)
+	s.loop()
+
+	expectInOrder(t, "output", out.String(), []string{"included ", "yew> U"})
+	expectInOrder(t, "errors", errs.String(), []string{"expected a path"})
+}
+
+func TestApi(t *testing.T) {
+	dir := t.TempDir()
+	bool := filepath.Join(dir, "bool.yew")
+	if err := os.WriteFile(bool, []byte("module bool\n\nopen Bool : Type where (\n  True : Bool\n  False : Bool\n)\n\npublic not : Bool -> Bool\nnot True = False\nnot False = True\n\nxor : Bool -> Bool -> Bool\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, errs := &bytes.Buffer{}, &bytes.Buffer{}
+	s := newSession(strings.NewReader(":api "+strings.TrimSuffix(bool, ".yew")+"\n:api "+filepath.Join(dir, "none")+"\n"), out, errs)
+	s.loop()
+
+	expectInOrder(t, "output", out.String(), []string{"yew> Bool : Type where", "yew>     True : Bool", "yew>     False : Bool", "yew> not : Bool -> Bool"})
+	if strings.Contains(out.String(), "xor") {
+		t.Errorf("expected private `xor` to be left out, got %q", out.String())
+	}
+	expectInOrder(t, "errors", errs.String(), []string{"Error (OS)"})
+}
+
+func expectInOrder(t *testing.T, what, actual string, expected []string) {
+	t.Helper()
+	rest := actual
+	for _, e := range expected {
+		i := strings.Index(rest, e)
+		if i < 0 {
+			t.Fatalf("expected %s to contain %q (in order), got %q", what, e, actual)
+		}
+		rest = rest[i+len(e):]
+	}
+}
+
+func TestRecord(t *testing.T) {
+	record := filepath.Join(t.TempDir(), "record.yew")
+	args := makeDefault()
+	args.Output = record
+
+	out, errs := &bytes.Buffer{}, &bytes.Buffer{}
+	s := newSession(strings.NewReader("Unit : Type where U : Unit\n:save\nv = U\n:restore\nu = U\nw = W\n:type u\n:begin\nid : a -> a\nid x = x\n:end\nid u\n"), out, errs)
+	s.reportErrors(s.load(args))
+	s.loop()
+
+	content, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rejected definitions, expressions, commands, and restored definitions are left out
+	expected := "Unit : Type where U : Unit\nu = U\nid : a -> a\nid x = x\n"
+	if string(content) != expected {
+		t.Errorf("expected %q, got %q", expected, string(content))
+	}
+	if res := parse.CheckFile(record); !res.Ok() {
+		t.Errorf("expected the record to compile, got %v", res.Errors)
+	}
+}
+
+// the record is only rewritten once the session's definitions change
+func TestRecordOnChange(t *testing.T) {
+	record := filepath.Join(t.TempDir(), "record.yew")
+	args := makeDefault()
+	args.Output = record
+
+	out, errs := &bytes.Buffer{}, &bytes.Buffer{}
+	s := newSession(strings.NewReader(""), out, errs)
+	s.reportErrors(s.load(args))
+	s.line("Unit : Type where U : Unit\n")
+	if err := os.Remove(record); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"U\n", ":type U\n", ":save\n", "w = W\n", ":set strategy lazy\n", ":api\n"} {
+		s.line(line)
+		if _, err := os.Stat(record); err == nil {
+			t.Fatalf("expected %q to leave the record unwritten", line)
+		}
+	}
+	for _, line := range []string{"u = U\n", ":restore\n"} {
+		s.line(line)
+		if _, err := os.Stat(record); err != nil {
+			t.Fatalf("expected %q to write the record, got %v", line, err)
+		}
+		if err := os.Remove(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLiterate(t *testing.T) {
+	dir := t.TempDir()
+	unit, record, output := filepath.Join(dir, "unit.lyew"), filepath.Join(dir, "record.lyew"), filepath.Join(dir, "record.yew")
+	if err := os.WriteFile(unit, []byte("The unit type:\n\n> Unit : Type where (\n>   U : Unit\n> )\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	args := makeDefault()
+	args.Literate = &record
+	args.Output = output
+
+	out, errs := &bytes.Buffer{}, &bytes.Buffer{}
+	s := newSession(strings.NewReader(":include "+strings.TrimSuffix(unit, ".lyew")+"\nprose is ignored\n> u = U\n```\nv = u\n```\n:run v\n"), out, errs)
+	s.reportErrors(s.load(args))
+	s.loop()
+
+	expectInOrder(t, "output", out.String(), []string{"included ", "yew> U"})
+	if errs.Len() != 0 {
+		t.Errorf("unexpected errors: %s", errs.String())
+	}
+	// bird tracks aren't recorded as indentation
+	expected := "Unit : Type where (\n  U : Unit\n)\nu = U\nv = u\n"
+	for path, content := range map[string]string{output: expected, record: source.BirdTrack(expected)} {
+		if actual, err := os.ReadFile(path); err != nil {
+			t.Fatal(err)
+		} else if string(actual) != content {
+			t.Errorf("expected %q, got %q", content, string(actual))
+		}
+	}
+	if res := parse.CheckFile(record); !res.Ok() {
+		t.Errorf("expected the literate record to compile, got %v", res.Errors)
+	}
+}
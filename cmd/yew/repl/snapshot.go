@@ -0,0 +1,84 @@
+package repl
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/petersalex27/yew/api"
+)
+
+// a copy of a session's environment, saved by `:save` and restored by `:restore`
+type snapshot struct {
+	// empty for a snapshot saved without a name
+	name string
+	// the session's definitions
+	defs     string
+	includes []api.Source
+	config   config
+	warned   map[string]bool
+}
+
+// returns the name `:save` and `:restore` respond with for the `i`-th snapshot
+func (s *session) snapshotName(i int) string {
+	if name := s.snapshots[i].name; name != "" {
+		return "`" + name + "`"
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// returns the index of the snapshot named `name`, or -1 if there's none
+func (s *session) findSnapshot(name string) int {
+	for i := len(s.snapshots) - 1; i >= 0; i-- {
+		if s.snapshots[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// pushes a snapshot of the session's environment, replacing any other snapshot named `name`
+func (s *session) save(name string) []error {
+	if strings.ContainsFunc(name, func(r rune) bool { return r == ' ' || r == '\t' }) {
+		return []error{fmt.Errorf("snapshot names cannot contain spaces, see `:help :save`")}
+	}
+	if i := s.findSnapshot(name); name != "" && i >= 0 {
+		s.snapshots = append(s.snapshots[:i], s.snapshots[i+1:]...)
+	}
+
+	s.snapshots = append(s.snapshots, snapshot{
+		name:     name,
+		defs:     s.defs,
+		includes: slices.Clone(s.includes),
+		config:   s.config,
+		warned:   maps.Clone(s.warned),
+	})
+	s.respond("saved snapshot " + s.snapshotName(len(s.snapshots)-1))
+	return nil
+}
+
+// restores the snapshot named `name`, keeping it so it can be restored again; if `name` is empty,
+// the most recent snapshot is popped and restored instead
+func (s *session) restore(name string) []error {
+	i := len(s.snapshots) - 1
+	if name != "" {
+		i = s.findSnapshot(name)
+	}
+	if i < 0 && name == "" {
+		return []error{fmt.Errorf("no snapshots are saved, see `:help :save`")}
+	} else if i < 0 {
+		return []error{fmt.Errorf("no snapshot is named `%s`", name)}
+	}
+
+	snap := s.snapshots[i]
+	s.defs = snap.defs
+	s.includes = slices.Clone(snap.includes)
+	s.config = snap.config
+	s.warned = maps.Clone(snap.warned)
+	s.respond("restored snapshot " + s.snapshotName(i))
+	if name == "" {
+		s.snapshots = s.snapshots[:i]
+	}
+	return s.record()
+}
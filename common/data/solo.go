@@ -24,6 +24,9 @@ func One[a api.Node](node a) Solo[a] {
 	return Solo[a]{one: node, Position: node.GetPos()}
 }
 
+// returns the node held by the `solo` node
+func (o Solo[a]) Extract() a { return o.one }
+
 func SoloMap[a, b api.Node](f func(a) b) func(Solo[a]) Solo[b] {
 	return func(s Solo[a]) Solo[b] {
 		return One(f(s.one))
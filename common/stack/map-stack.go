@@ -114,6 +114,19 @@ func (s *MapStack[a, b]) Exists(key a) (found bool) {
 	return found
 }
 
+// searches for the key in the maps starting from the top and going down, returning the value
+// associated with the first occurrence of the key and whether the key was found
+//
+// unlike `CopyUp`, this does not modify the stack
+func (s *MapStack[a, b]) Find(key a) (val b, found bool) {
+	for i := s.ctr - 1; i >= 0; i-- {
+		if val, found = s.data[i][key]; found {
+			return val, found
+		}
+	}
+	return val, false
+}
+
 // searches for the key in the maps starting from the top and going down
 //
 // if found, copies the key-value pair to the top map and returns true
@@ -207,3 +220,15 @@ func (s *MapStack[a, b]) Fold(z b, f func(b, b) b) b {
 	}
 	return z
 }
+
+// returns a new map stack holding the same maps as `s`
+//
+// the maps themselves are shared, so mapping a key in a map held by both stacks is visible to both,
+// but pushing or popping a map only affects the stack it's done to
+func (s *MapStack[a, b]) Share() *MapStack[a, b] {
+	data := make([]map[a]b, s.ctr)
+	copy(data, s.data[:s.ctr])
+	return &MapStack[a, b]{
+		Stack: &Stack[map[a]b]{cap: s.ctr, ctr: s.ctr, data: data},
+	}
+}
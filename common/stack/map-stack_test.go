@@ -0,0 +1,56 @@
+package stack
+
+import (
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	s := NewMap[string, int]()
+	s.Map("x", 1)
+	s.Map("y", 2)
+	s.Push(make(map[string]int))
+	s.Map("x", 3)
+
+	tests := []struct {
+		key   string
+		val   int
+		found bool
+	}{
+		{"x", 3, true},
+		{"y", 2, true},
+		{"z", 0, false},
+	}
+
+	for _, test := range tests {
+		val, found := s.Find(test.key)
+		if found != test.found || val != test.val {
+			t.Errorf("Expected Find(%q)=(%d, %t), got (%d, %t)", test.key, test.val, test.found, val, found)
+		}
+	}
+
+	if s.Len() != 2 {
+		t.Errorf("Expected s.Len()=2, got %d", s.Len())
+	}
+}
+
+func TestShare(t *testing.T) {
+	s := NewMap[string, int]()
+	s.Map("x", 1)
+	shared := s.Share()
+
+	s.Map("y", 2)
+	if val, found := shared.Find("y"); !found || val != 2 {
+		t.Errorf("Expected shared.Find(\"y\")=(2, true), got (%d, %t)", val, found)
+	}
+
+	shared.Push(make(map[string]int))
+	shared.Map("z", 3)
+	if s.Exists("z") || s.Len() != 1 {
+		t.Errorf("Expected pushing to the shared stack to leave the original unchanged")
+	}
+
+	s.Pop()
+	if val, found := shared.Find("x"); !found || val != 1 {
+		t.Errorf("Expected shared.Find(\"x\")=(1, true), got (%d, %t)", val, found)
+	}
+}
@@ -0,0 +1,170 @@
+// core language: the untyped terms a yew program is lowered into once it has been checked
+package core
+
+// a term of the core language
+type Term interface{ term() }
+
+// a pattern of the core language
+type Pattern interface{ pattern() }
+
+// kind of a literal value
+type LitKind int
+
+const (
+	IntLit LitKind = iota
+	FloatLit
+	CharLit
+	StringLit
+)
+
+type (
+	// a reference to a bound name, e.g., `x`
+	Var struct{ Name string }
+
+	// a literal value as written in the source, e.g., `1_000` or `"hello"`
+	Lit struct {
+		Kind  LitKind
+		Value string
+	}
+
+	// an application of a function to one or more arguments, e.g., `f x y`
+	App struct {
+		Fun  Term
+		Args []Term
+	}
+
+	// a lambda abstraction, e.g., `\x, (Cons y _) => y`
+	Lam struct {
+		Params []Pattern
+		Body   Term
+		// decides whether the parameters match, nil until compiled
+		//
+		// SEE: `Program.CompileMatches`
+		Tree Decision
+	}
+
+	// a recursive group of bindings scoped over a term, e.g., a let expression or a where clause
+	Let struct {
+		Funcs []Func
+		Body  Term
+	}
+
+	// a case expression, e.g., `case xs of (Nil => 0; Cons x _ => x)`
+	Case struct {
+		Scrutinee Term
+		// arms of the case expression, each with exactly one pattern
+		Arms []Clause
+		// decides which arm matches, nil until compiled
+		//
+		// SEE: `Program.CompileMatches`
+		Tree Decision
+	}
+
+	// a term that fails when evaluated, e.g., a typed hole
+	Fail struct{ Reason string }
+)
+
+type (
+	// binds the matched value to a name, e.g., `x`
+	PVar struct{ Name string }
+
+	// matches any value without binding it, e.g., `_`
+	PWild struct{}
+
+	// matches a literal value, e.g., `0`
+	PLit struct{ Lit Lit }
+
+	// matches a value built by a constructor, e.g., `Cons x xs`
+	PCon struct {
+		Name string
+		Args []Pattern
+	}
+
+	// an inaccessible pattern, e.g., `.n`; like a wildcard, it matches any value without testing it,
+	// since the other patterns already determine its value
+	PAccess struct{ Name string }
+)
+
+func (Var) term()  {}
+func (Lit) term()  {}
+func (App) term()  {}
+func (Lam) term()  {}
+func (Let) term()  {}
+func (Case) term() {}
+func (Fail) term() {}
+
+func (PVar) pattern()    {}
+func (PWild) pattern()   {}
+func (PLit) pattern()    {}
+func (PCon) pattern()    {}
+func (PAccess) pattern() {}
+
+// a clause of a function or case expression: the body evaluated when each pattern matches its
+// argument
+type Clause struct {
+	Patterns []Pattern
+	Body     Term
+}
+
+// a function defined by one or more clauses, e.g.,
+//
+//	ifThenElse True t _ = t
+//	ifThenElse False _ f = f
+//
+// a function without parameters (a single clause with no patterns) is a value
+type Func struct {
+	Name    string
+	Clauses []Clause
+	// decides which clause matches, nil until compiled
+	//
+	// SEE: `Program.CompileMatches`
+	Tree Decision
+}
+
+// returns the number of arguments the function matches on
+func (f Func) Arity() int {
+	if len(f.Clauses) == 0 {
+		return 0
+	}
+	return len(f.Clauses[0].Patterns)
+}
+
+// a constructor of a data type
+type Constructor struct {
+	Name string
+	// name of the data type the constructor builds values of
+	Type string
+	// number of explicit arguments the constructor takes
+	Arity int
+}
+
+// an implementation of a spec member for the values of one type
+type Instance struct {
+	// name of the type the instance is for, e.g., `List` for `inst Eq a => Eq (List a)`; empty when
+	// the instance is for every type, e.g., `inst Show a`
+	Type string
+	Func Func
+}
+
+// a member of a spec, implemented separately by each instance of the spec
+type Method struct {
+	Name      string
+	Instances []Instance
+	// default implementation given by the spec, nil if there isn't one
+	Default *Func
+}
+
+// a lowered yew program
+type Program struct {
+	Constructors map[string]Constructor
+	Funcs        map[string]Func
+	Methods      map[string]*Method
+}
+
+func NewProgram() Program {
+	return Program{
+		Constructors: make(map[string]Constructor),
+		Funcs:        make(map[string]Func),
+		Methods:      make(map[string]*Method),
+	}
+}
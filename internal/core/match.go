@@ -0,0 +1,296 @@
+package core
+
+import "slices"
+
+// a decision tree: decides which clause of a function or case expression matches its arguments by
+// testing each argument at most once
+type Decision interface{ decision() }
+
+// a position inside the arguments being matched: the first index is the argument, and each
+// following index is a field of the constructor found at the position before it, e.g., the
+// occurrence of `y` in `f (Cons _ (Cons y _))` is `[0, 1, 0]`
+type Occurrence []int
+
+type (
+	// no clause matches the arguments
+	Unmatched struct{}
+
+	// the clause `Clause` matches the arguments
+	Leaf struct {
+		// index of the matching clause
+		Clause int
+		// values the clause's patterns bind
+		Bindings []Binding
+	}
+
+	// tests the value at `Occurrence` and continues with the branch it selects
+	Switch struct {
+		Occurrence   Occurrence
+		Constructors []ConstructorBranch
+		Literals     []LiteralBranch
+		// taken when no branch is selected, nil when the branches cover every possible value
+		Default Decision
+	}
+)
+
+// binds the value at `Occurrence` to `Name`
+type Binding struct {
+	Name       string
+	Occurrence Occurrence
+}
+
+// selected when the tested value was built by the constructor `Name`; the fields of the value are
+// at the tested occurrence extended by the field's index
+type ConstructorBranch struct {
+	Name  string
+	Arity int
+	Next  Decision
+}
+
+// selected when the tested value equals `Lit`
+type LiteralBranch struct {
+	Lit  Lit
+	Next Decision
+}
+
+func (Unmatched) decision() {}
+func (Leaf) decision()      {}
+func (Switch) decision()    {}
+
+// compiles clauses into a decision tree
+//
+// clauses are tried in order, so the tree selects the first clause that matches. `constructors`
+// decides whether a switch on constructors covers every value of their type
+func Compile(clauses []Clause, constructors map[string]Constructor) Decision {
+	m := matrix{constructors: constructors}
+	arity := 0
+	if len(clauses) != 0 {
+		arity = len(clauses[0].Patterns)
+	}
+	for i, clause := range clauses {
+		m.rows = append(m.rows, row{patterns: slices.Clone(clause.Patterns), clause: i})
+	}
+	for i := 0; i < arity; i++ {
+		m.occurrences = append(m.occurrences, Occurrence{i})
+	}
+	return m.compile()
+}
+
+// the clauses not yet ruled out, each with the patterns left to test for the values at
+// `occurrences`
+type matrix struct {
+	constructors map[string]Constructor
+	occurrences  []Occurrence
+	rows         []row
+}
+
+type row struct {
+	patterns []Pattern
+	bindings []Binding
+	clause   int
+}
+
+// returns true iff `pat` matches every value without testing it
+func irrefutable(pat Pattern) bool {
+	switch pat.(type) {
+	case PVar, PWild, PAccess:
+		return true
+	}
+	return false
+}
+
+// returns `r` without its `i`-th pattern, binding the pattern if it's a variable
+func (r row) remove(i int, at Occurrence) row {
+	if v, isVar := r.patterns[i].(PVar); isVar {
+		r.bindings = append(slices.Clip(r.bindings), Binding{Name: v.Name, Occurrence: at})
+	}
+	r.patterns = slices.Delete(slices.Clone(r.patterns), i, i+1)
+	return r
+}
+
+func (m matrix) compile() Decision {
+	if len(m.rows) == 0 {
+		return Unmatched{}
+	}
+
+	first := m.rows[0]
+	column := slices.IndexFunc(first.patterns, func(pat Pattern) bool { return !irrefutable(pat) })
+	if column < 0 {
+		bindings := slices.Clip(first.bindings)
+		for i, pat := range first.patterns {
+			if v, isVar := pat.(PVar); isVar {
+				bindings = append(bindings, Binding{Name: v.Name, Occurrence: m.occurrences[i]})
+			}
+		}
+		return Leaf{Clause: first.clause, Bindings: bindings}
+	}
+	return m.split(column)
+}
+
+// tests the values at the occurrence of `column`, branching on each constructor and literal any
+// clause tests for there
+func (m matrix) split(column int) Decision {
+	at := m.occurrences[column]
+	s := Switch{Occurrence: at}
+	var seen []string
+	for _, r := range m.rows {
+		switch p := r.patterns[column].(type) {
+		case PCon:
+			if !slices.Contains(seen, p.Name) {
+				seen = append(seen, p.Name)
+				s.Constructors = append(s.Constructors, ConstructorBranch{Name: p.Name, Arity: len(p.Args), Next: m.specialize(column, p.Name, len(p.Args))})
+			}
+		case PLit:
+			if !slices.ContainsFunc(s.Literals, func(b LiteralBranch) bool { return b.Lit == p.Lit }) {
+				s.Literals = append(s.Literals, LiteralBranch{Lit: p.Lit, Next: m.specializeLiteral(column, p.Lit)})
+			}
+		}
+	}
+
+	if len(s.Literals) != 0 || !m.covers(seen) {
+		s.Default = m.fallback(column)
+	}
+	return s
+}
+
+// returns true iff `names` includes every constructor of their type
+func (m matrix) covers(names []string) bool {
+	if len(names) == 0 {
+		return false
+	}
+
+	ty := m.constructors[names[0]].Type
+	for name, constructor := range m.constructors {
+		if constructor.Type == ty && !slices.Contains(names, name) {
+			return false
+		}
+	}
+	// unknown constructors never cover their type
+	for _, name := range names {
+		if _, found := m.constructors[name]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// returns the tree for values at `column` built by the constructor `name`: the clauses that test
+// for `name` there or don't test the column at all, with the constructor's fields in place of the
+// column
+func (m matrix) specialize(column int, name string, arity int) Decision {
+	at := m.occurrences[column]
+	next := matrix{constructors: m.constructors, occurrences: slices.Delete(slices.Clone(m.occurrences), column, column+1)}
+	for i := 0; i < arity; i++ {
+		next.occurrences = append(next.occurrences, append(slices.Clone(at), i))
+	}
+
+	for _, r := range m.rows {
+		var fields []Pattern
+		switch p := r.patterns[column].(type) {
+		case PCon:
+			if p.Name != name || len(p.Args) != arity {
+				continue
+			}
+			fields = p.Args
+		case PLit:
+			continue
+		default:
+			fields = make([]Pattern, arity)
+			for i := range fields {
+				fields[i] = PWild{}
+			}
+		}
+
+		r = r.remove(column, at)
+		r.patterns = append(r.patterns, fields...)
+		next.rows = append(next.rows, r)
+	}
+	return next.compile()
+}
+
+// returns the tree for values at `column` equal to `lit`
+func (m matrix) specializeLiteral(column int, lit Lit) Decision {
+	next := matrix{constructors: m.constructors, occurrences: slices.Delete(slices.Clone(m.occurrences), column, column+1)}
+	for _, r := range m.rows {
+		if p, isLit := r.patterns[column].(PLit); isLit && p.Lit != lit {
+			continue
+		} else if _, isCon := r.patterns[column].(PCon); isCon {
+			continue
+		}
+		next.rows = append(next.rows, r.remove(column, m.occurrences[column]))
+	}
+	return next.compile()
+}
+
+// returns the tree for values at `column` that no branch selects: the clauses that don't test the
+// column
+func (m matrix) fallback(column int) Decision {
+	next := matrix{constructors: m.constructors, occurrences: slices.Delete(slices.Clone(m.occurrences), column, column+1)}
+	for _, r := range m.rows {
+		if irrefutable(r.patterns[column]) {
+			next.rows = append(next.rows, r.remove(column, m.occurrences[column]))
+		}
+	}
+	return next.compile()
+}
+
+// compiles the clauses of every function, lambda abstraction, and case expression in the program,
+// filling in their `Tree`s
+func (program Program) CompileMatches() {
+	c := compiler{program.Constructors}
+	for name, f := range program.Funcs {
+		program.Funcs[name] = c.function(f)
+	}
+	for _, m := range program.Methods {
+		for i := range m.Instances {
+			m.Instances[i].Func = c.function(m.Instances[i].Func)
+		}
+		if m.Default != nil {
+			f := c.function(*m.Default)
+			m.Default = &f
+		}
+	}
+}
+
+// fills in the decision trees of the terms in a program
+type compiler struct{ constructors map[string]Constructor }
+
+func (c compiler) function(f Func) Func {
+	f.Clauses = c.clauses(f.Clauses)
+	f.Tree = Compile(f.Clauses, c.constructors)
+	return f
+}
+
+func (c compiler) clauses(clauses []Clause) []Clause {
+	out := make([]Clause, len(clauses))
+	for i, clause := range clauses {
+		out[i] = Clause{Patterns: clause.Patterns, Body: c.term(clause.Body)}
+	}
+	return out
+}
+
+func (c compiler) term(t Term) Term {
+	switch x := t.(type) {
+	case App:
+		args := make([]Term, len(x.Args))
+		for i, arg := range x.Args {
+			args[i] = c.term(arg)
+		}
+		return App{Fun: c.term(x.Fun), Args: args}
+	case Lam:
+		x.Body = c.term(x.Body)
+		x.Tree = Compile([]Clause{{Patterns: x.Params, Body: x.Body}}, c.constructors)
+		return x
+	case Let:
+		funcs := make([]Func, len(x.Funcs))
+		for i, f := range x.Funcs {
+			funcs[i] = c.function(f)
+		}
+		return Let{Funcs: funcs, Body: c.term(x.Body)}
+	case Case:
+		x.Scrutinee, x.Arms = c.term(x.Scrutinee), c.clauses(x.Arms)
+		x.Tree = Compile(x.Arms, c.constructors)
+		return x
+	}
+	return t
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testConstructors = map[string]Constructor{
+	"True":  {Name: "True", Type: "Bool"},
+	"False": {Name: "False", Type: "Bool"},
+	"Nil":   {Name: "Nil", Type: "List"},
+	"Cons":  {Name: "Cons", Type: "List", Arity: 2},
+}
+
+func clause(patterns ...Pattern) Clause { return Clause{Patterns: patterns, Body: Var{"x"}} }
+
+func con(name string, args ...Pattern) Pattern { return PCon{Name: name, Args: args} }
+
+func TestCompile(t *testing.T) {
+	x, y, wild := PVar{"x"}, PVar{"y"}, PWild{}
+	zero, one := Lit{IntLit, "0"}, Lit{IntLit, "1"}
+
+	tests := []struct {
+		name     string
+		clauses  []Clause
+		expected Decision
+	}{
+		{"no clauses", nil, Unmatched{}},
+		{
+			"variable",
+			[]Clause{clause(x)},
+			Leaf{Clause: 0, Bindings: []Binding{{"x", Occurrence{0}}}},
+		},
+		{
+			"complete",
+			[]Clause{clause(con("True"), x, wild), clause(con("False"), wild, y)},
+			Switch{
+				Occurrence: Occurrence{0},
+				Constructors: []ConstructorBranch{
+					{"True", 0, Leaf{Clause: 0, Bindings: []Binding{{"x", Occurrence{1}}}}},
+					{"False", 0, Leaf{Clause: 1, Bindings: []Binding{{"y", Occurrence{2}}}}},
+				},
+			},
+		},
+		{
+			"incomplete",
+			[]Clause{clause(con("True"))},
+			Switch{
+				Occurrence:   Occurrence{0},
+				Constructors: []ConstructorBranch{{"True", 0, Leaf{Clause: 0}}},
+				Default:      Unmatched{},
+			},
+		},
+		{
+			"default",
+			[]Clause{clause(con("True")), clause(x)},
+			Switch{
+				Occurrence:   Occurrence{0},
+				Constructors: []ConstructorBranch{{"True", 0, Leaf{Clause: 0}}},
+				Default:      Leaf{Clause: 1, Bindings: []Binding{{"x", Occurrence{0}}}},
+			},
+		},
+		{
+			"nested",
+			[]Clause{clause(con("Cons", x, con("Nil"))), clause(wild)},
+			Switch{
+				Occurrence: Occurrence{0},
+				Constructors: []ConstructorBranch{{"Cons", 2, Switch{
+					Occurrence:   Occurrence{0, 1},
+					Constructors: []ConstructorBranch{{"Nil", 0, Leaf{Clause: 0, Bindings: []Binding{{"x", Occurrence{0, 0}}}}}},
+					Default:      Leaf{Clause: 1},
+				}}},
+				Default: Leaf{Clause: 1},
+			},
+		},
+		{
+			"literals",
+			[]Clause{clause(PLit{zero}), clause(PLit{one}), clause(PAccess{"n"})},
+			Switch{
+				Occurrence: Occurrence{0},
+				Literals:   []LiteralBranch{{zero, Leaf{Clause: 0}}, {one, Leaf{Clause: 1}}},
+				Default:    Leaf{Clause: 2},
+			},
+		},
+		{
+			"first clause wins",
+			[]Clause{clause(x), clause(con("True"))},
+			Leaf{Clause: 0, Bindings: []Binding{{"x", Occurrence{0}}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := Compile(test.clauses, testConstructors)
+			if !reflect.DeepEqual(normalize(actual), normalize(test.expected)) {
+				t.Errorf("expected %#v, got %#v", test.expected, actual)
+			}
+		})
+	}
+}
+
+// replaces empty binding lists with nil so trees compare equal regardless of how they were built
+func normalize(d Decision) Decision {
+	switch x := d.(type) {
+	case Leaf:
+		if len(x.Bindings) == 0 {
+			x.Bindings = nil
+		}
+		return x
+	case Switch:
+		cons := make([]ConstructorBranch, len(x.Constructors))
+		for i, branch := range x.Constructors {
+			branch.Next = normalize(branch.Next)
+			cons[i] = branch
+		}
+		lits := make([]LiteralBranch, len(x.Literals))
+		for i, branch := range x.Literals {
+			branch.Next = normalize(branch.Next)
+			lits[i] = branch
+		}
+		x.Constructors, x.Literals = cons, lits
+		if x.Default != nil {
+			x.Default = normalize(x.Default)
+		}
+		return x
+	}
+	return d
+}
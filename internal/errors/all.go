@@ -22,6 +22,13 @@ func windowWarning(s api.SourceCode, msg string, start, end int) error {
 	return errors.New(e)
 }
 
+// appends a note pointing to a related location to `err`
+func WithNote(err error, s api.SourceCode, msg string, start, end int) error {
+	window := util.Window(s, start, end)
+	line, char := util.CalcLocation(s, start, false)
+	return fmt.Errorf("%w\n[%d:%d] Note: %s\n%s", err, line, char, msg, window)
+}
+
 func Syntax(s api.SourceCode, msg string, start, end int) error {
 	return windowError(s, "Syntax", msg, start, end)
 }
@@ -38,6 +45,14 @@ func Lexical(s api.SourceCode, msg string, start, end int) error {
 	return windowError(s, "Lexical", msg, start, end)
 }
 
+func Name(s api.SourceCode, msg string, start, end int) error {
+	return windowError(s, "Name", msg, start, end)
+}
+
 func OS(msg string) error {
 	return errors.New(fmt.Sprintf("Error (OS): %s", msg))
 }
+
+func Runtime(msg string) error {
+	return errors.New(fmt.Sprintf("Error (Runtime): %s", msg))
+}
@@ -0,0 +1,11 @@
+package interpreter
+
+const (
+	BadLiteral   string = "malformed literal"               // bad-literal
+	InfiniteLoop string = "value depends on itself"         // infinite-loop
+	NoInstance   string = "no instance for the arguments"   // no-instance
+	NoMatch      string = "no clause matches the arguments" // no-match
+	NotAFunction string = "value cannot be applied"         // not-a-function
+	Unbound      string = "name has no definition"          // unbound
+	UnknownTerm  string = "unknown term"                    // unknown-term
+)
@@ -0,0 +1,458 @@
+// =================================================================================================
+// interpreter: evaluates the core language a checked yew source is lowered into
+// =================================================================================================
+
+package interpreter
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/petersalex27/yew/common/stack"
+	"github.com/petersalex27/yew/internal/core"
+	"github.com/petersalex27/yew/internal/errors"
+)
+
+// when arguments and bindings are evaluated
+type Strategy int
+
+const (
+	// arguments and bindings are evaluated before they're bound
+	Strict Strategy = iota
+	// arguments and bindings are evaluated the first time their value is needed
+	Lazy
+)
+
+func (s Strategy) String() string {
+	if s == Lazy {
+		return "lazy"
+	}
+	return "strict"
+}
+
+type Interpreter struct {
+	strategy Strategy
+	// every constructor defined, used to compile clauses without decision trees
+	constructors map[string]core.Constructor
+	// global definitions: constructors, functions, and spec members
+	globals *Env
+}
+
+// creates an interpreter for a lowered program
+func New(program core.Program, strategy Strategy) *Interpreter {
+	in := &Interpreter{strategy: strategy, constructors: make(map[string]core.Constructor), globals: stack.NewMap[string, Value]()}
+	in.globals.Push(make(map[string]Value))
+	in.Define(program)
+	return in
+}
+
+// adds the definitions of a lowered program to the interpreter's globals, replacing any existing
+// definitions with the same name
+func (in *Interpreter) Define(program core.Program) {
+	for name, constructor := range program.Constructors {
+		in.constructors[name] = constructor
+		if constructor.Arity == 0 {
+			in.globals.Map(name, &Data{Constructor: constructor})
+		} else {
+			in.globals.Map(name, ConstructorFunc{constructor})
+		}
+	}
+
+	for name, f := range program.Funcs {
+		in.globals.Map(name, in.function(f, in.globals))
+	}
+
+	for name, m := range program.Methods {
+		method := &Method{Name: name}
+		for _, inst := range m.Instances {
+			method.instances = append(method.instances, instance{inst.Type, in.function(inst.Func, in.globals)})
+		}
+		if m.Default != nil {
+			method.fallback = in.function(*m.Default, in.globals)
+		}
+		in.globals.Map(name, method)
+	}
+}
+
+// evaluates `term` and everything its value is built from, e.g., the fields of a constructor
+func (in *Interpreter) Eval(term core.Term) (Value, error) {
+	v, err := in.eval(term, in.globals)
+	if err != nil {
+		return nil, err
+	}
+	return in.deepForce(v)
+}
+
+// evaluates the global definition `name`, e.g., `main`
+func (in *Interpreter) Run(name string) (Value, error) {
+	return in.Eval(core.Var{Name: name})
+}
+
+func runtimeError(msg, detail string) error {
+	if detail == "" {
+		return errors.Runtime(msg)
+	}
+	return errors.Runtime(msg + ": " + detail)
+}
+
+// returns the value of a function definition: a closure, or, for a definition without parameters,
+// a suspended evaluation of its body
+func (in *Interpreter) function(f core.Func, env *Env) Value {
+	if f.Arity() == 0 {
+		if len(f.Clauses) == 0 {
+			return &Thunk{term: core.Fail{Reason: "`" + f.Name + "` has no clauses"}, env: env}
+		}
+		return &Thunk{term: f.Clauses[0].Body, env: env}
+	}
+	return &Closure{Name: f.Name, Clauses: f.Clauses, Tree: f.Tree, env: env}
+}
+
+// returns `env` extended with `bindings`
+func extend(env *Env, bindings map[string]Value) *Env {
+	extended := env.Share()
+	extended.Push(bindings)
+	return extended
+}
+
+// evaluates `term` to weak head normal form, i.e., no thunk is returned, but the arguments of
+// constructors and partial applications may still be suspended
+func (in *Interpreter) eval(term core.Term, env *Env) (Value, error) {
+	switch t := term.(type) {
+	case core.Var:
+		v, found := env.Find(t.Name)
+		if !found {
+			return nil, runtimeError(Unbound, t.Name)
+		}
+		return in.force(v)
+	case core.Lit:
+		return literal(t)
+	case core.App:
+		return in.evalApp(t, env)
+	case core.Lam:
+		return &Closure{Clauses: []core.Clause{{Patterns: t.Params, Body: t.Body}}, Tree: t.Tree, env: env}, nil
+	case core.Let:
+		return in.evalLet(t, env)
+	case core.Case:
+		return in.evalCase(t, env)
+	case core.Fail:
+		return nil, errors.Runtime(t.Reason)
+	}
+	return nil, runtimeError(UnknownTerm, "")
+}
+
+// returns `term` as an argument or binding: evaluated when strict and, when lazy, suspended unless
+// evaluating it is trivial
+func (in *Interpreter) delay(term core.Term, env *Env) (Value, error) {
+	if in.strategy == Strict {
+		return in.eval(term, env)
+	}
+
+	switch t := term.(type) {
+	case core.Var:
+		if v, found := env.Find(t.Name); found {
+			return v, nil
+		}
+	case core.Lit, core.Lam:
+		return in.eval(term, env)
+	}
+	return &Thunk{term: term, env: env}, nil
+}
+
+// returns `v` if it's evaluated, the value of `v` if it's a forced thunk, and nil otherwise
+func evaluated(v Value) Value {
+	t, isThunk := v.(*Thunk)
+	if !isThunk {
+		return v
+	} else if t.state == forced {
+		return t.value
+	}
+	return nil
+}
+
+// evaluates a suspended value to weak head normal form, returning any other value as is
+func (in *Interpreter) force(v Value) (Value, error) {
+	t, isThunk := v.(*Thunk)
+	if !isThunk {
+		return v, nil
+	}
+
+	switch t.state {
+	case forced:
+		return t.value, nil
+	case forcing:
+		return nil, runtimeError(InfiniteLoop, "")
+	}
+
+	t.state = forcing
+	value, err := in.eval(t.term, t.env)
+	if err != nil {
+		t.state = suspended
+		return nil, err
+	}
+	// drop the term and environment so they can be collected
+	t.state, t.value, t.term, t.env = forced, value, nil, nil
+	return value, nil
+}
+
+// forces `v` and, recursively, the fields of the constructors it's built from
+func (in *Interpreter) deepForce(v Value) (Value, error) {
+	v, err := in.force(v)
+	if err != nil {
+		return nil, err
+	}
+
+	data, isData := v.(*Data)
+	if !isData {
+		return v, nil
+	}
+	for i, field := range data.Fields {
+		if data.Fields[i], err = in.deepForce(field); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func (in *Interpreter) evalApp(app core.App, env *Env) (Value, error) {
+	f, err := in.eval(app.Fun, env)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]Value, len(app.Args))
+	for i, arg := range app.Args {
+		if args[i], err = in.delay(arg, env); err != nil {
+			return nil, err
+		}
+	}
+	return in.apply(f, args)
+}
+
+// applies `f` to `args`, returning a partial application if there are too few arguments
+func (in *Interpreter) apply(f Value, args []Value) (_ Value, err error) {
+	for len(args) != 0 {
+		if f, err = in.force(f); err != nil {
+			return nil, err
+		}
+
+		switch fn := f.(type) {
+		case *Closure:
+			arity := fn.Arity()
+			if len(args) < arity {
+				return &Partial{Fun: fn, Args: args}, nil
+			}
+			if f, err = in.call(fn, args[:arity]); err != nil {
+				return nil, err
+			}
+			args = args[arity:]
+		case *Partial:
+			f, args = fn.Fun, append(slices.Clone(fn.Args), args...)
+		case ConstructorFunc:
+			if len(args) < fn.Arity {
+				return &Partial{Fun: fn, Args: args}, nil
+			}
+			f = &Data{Constructor: fn.Constructor, Fields: slices.Clone(args[:fn.Arity])}
+			args = args[fn.Arity:]
+		case *Method:
+			if f, err = in.dispatch(fn, args); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, runtimeError(NotAFunction, f.String())
+		}
+	}
+	return in.force(f)
+}
+
+// returns the implementation of `m` for the type of the first argument that has an instance
+//
+// arguments are only forced when the implementation can't be chosen from the arguments that are
+// already evaluated, and then only until one of them has an instance
+func (in *Interpreter) dispatch(m *Method, args []Value) (Value, error) {
+	for _, arg := range args {
+		if fun, found := m.implementationFor(evaluated(arg)); found {
+			return fun, nil
+		}
+	}
+
+	if m.hasTypedInstance() {
+		for _, arg := range args {
+			if evaluated(arg) != nil {
+				continue // already tried
+			}
+			v, err := in.force(arg)
+			if err != nil {
+				return nil, err
+			}
+			if fun, found := m.implementationFor(v); found {
+				return fun, nil
+			}
+		}
+	}
+
+	for _, inst := range m.instances {
+		if inst.typ == "" {
+			return inst.fun, nil
+		}
+	}
+	if m.fallback != nil {
+		return m.fallback, nil
+	}
+	return nil, runtimeError(NoInstance, m.Name)
+}
+
+// calls a closure with exactly as many arguments as it takes, evaluating the body of the clause its
+// decision tree selects
+func (in *Interpreter) call(c *Closure, args []Value) (Value, error) {
+	clause, bindings, err := in.decide(in.tree(c.Tree, c.Clauses), args)
+	if err != nil {
+		return nil, err
+	} else if clause < 0 && c.Name == "" {
+		return nil, runtimeError(NoMatch, "")
+	} else if clause < 0 {
+		return nil, runtimeError(NoMatch, c.Name)
+	}
+	return in.eval(c.Clauses[clause].Body, extend(c.env, bindings))
+}
+
+func (in *Interpreter) evalLet(let core.Let, env *Env) (Value, error) {
+	bindings := make(map[string]Value, len(let.Funcs))
+	// bindings are in scope of each other, so the bindings are recursive
+	env = extend(env, bindings)
+	for _, f := range let.Funcs {
+		bindings[f.Name] = in.function(f, env)
+	}
+
+	if in.strategy == Strict {
+		for _, f := range let.Funcs {
+			if _, err := in.force(bindings[f.Name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return in.eval(let.Body, env)
+}
+
+func (in *Interpreter) evalCase(c core.Case, env *Env) (Value, error) {
+	scrutinee, err := in.delay(c.Scrutinee, env)
+	if err != nil {
+		return nil, err
+	}
+
+	clause, bindings, err := in.decide(in.tree(c.Tree, c.Arms), []Value{scrutinee})
+	if err != nil {
+		return nil, err
+	} else if clause < 0 {
+		return nil, runtimeError(NoMatch, "case expression")
+	}
+	return in.eval(c.Arms[clause].Body, extend(env, bindings))
+}
+
+// returns `tree`, compiling `clauses` when the tree hasn't been compiled
+func (in *Interpreter) tree(tree core.Decision, clauses []core.Clause) core.Decision {
+	if tree == nil {
+		return core.Compile(clauses, in.constructors)
+	}
+	return tree
+}
+
+// walks a decision tree, returning the index of the clause that matches `args` and the values its
+// patterns bind--or -1 if no clause matches
+//
+// values are only forced when the tree tests them
+func (in *Interpreter) decide(tree core.Decision, args []Value) (clause int, bindings map[string]Value, err error) {
+	for {
+		switch d := tree.(type) {
+		case core.Leaf:
+			bindings = make(map[string]Value, len(d.Bindings))
+			for _, binding := range d.Bindings {
+				if bindings[binding.Name], err = in.at(args, binding.Occurrence); err != nil {
+					return -1, nil, err
+				}
+			}
+			return d.Clause, bindings, nil
+		case core.Switch:
+			if tree, err = in.branch(d, args); err != nil {
+				return -1, nil, err
+			}
+		default:
+			return -1, nil, nil
+		}
+	}
+}
+
+// returns the branch of a switch selected by the value it tests
+func (in *Interpreter) branch(s core.Switch, args []Value) (core.Decision, error) {
+	v, err := in.at(args, s.Occurrence)
+	if err == nil {
+		v, err = in.force(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if data, isData := v.(*Data); isData {
+		for _, branch := range s.Constructors {
+			if branch.Name == data.Constructor.Name && branch.Arity == len(data.Fields) {
+				return branch.Next, nil
+			}
+		}
+	}
+
+	for _, branch := range s.Literals {
+		lit, err := literal(branch.Lit)
+		if err != nil {
+			return nil, err
+		} else if v == lit {
+			return branch.Next, nil
+		}
+	}
+
+	if s.Default == nil {
+		return core.Unmatched{}, nil
+	}
+	return s.Default, nil
+}
+
+// returns the (possibly suspended) value at `occurrence`, forcing the constructors on the way to it
+//
+// the tree only refers to the fields of a constructor after testing for it, so each value on the
+// way is data with enough fields
+func (in *Interpreter) at(args []Value, occurrence core.Occurrence) (Value, error) {
+	v := args[occurrence[0]]
+	for _, field := range occurrence[1:] {
+		forced, err := in.force(v)
+		if err != nil {
+			return nil, err
+		}
+		v = forced.(*Data).Fields[field]
+	}
+	return v, nil
+}
+
+// returns the value a literal denotes
+func literal(lit core.Lit) (Value, error) {
+	switch lit.Kind {
+	case core.IntLit:
+		i, err := strconv.ParseInt(strings.ReplaceAll(lit.Value, "_", ""), 0, 64)
+		if err != nil {
+			return nil, runtimeError(BadLiteral, lit.Value)
+		}
+		return Int(i), nil
+	case core.FloatLit:
+		f, err := strconv.ParseFloat(strings.ReplaceAll(lit.Value, "_", ""), 64)
+		if err != nil {
+			return nil, runtimeError(BadLiteral, lit.Value)
+		}
+		return Float(f), nil
+	case core.CharLit:
+		r, size := utf8.DecodeRuneInString(lit.Value)
+		if size == 0 || r == utf8.RuneError {
+			return nil, runtimeError(BadLiteral, lit.Value)
+		}
+		return Char(r), nil
+	}
+	return String(lit.Value), nil
+}
@@ -0,0 +1,147 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/petersalex27/yew/api/util"
+	"github.com/petersalex27/yew/internal/core"
+	"github.com/petersalex27/yew/internal/lexer"
+	"github.com/petersalex27/yew/internal/parser"
+)
+
+const prelude = `Nat : Type where (
+  Zero : Nat
+  Succ : Nat -> Nat
+)
+
+Bool : Type where (
+  True : Bool
+  False : Bool
+)
+
+List : Type -> Type where (
+  Nil : List a
+  Cons : a -> List a -> List a
+)
+
+spec Eq a where (
+  (==) : a -> a -> Bool
+)
+
+Color : Type where (
+  Red : Color
+  Blue : Color
+) deriving Eq Color
+
+ifThenElse : Bool -> a -> a -> a
+ifThenElse True t _ = t
+ifThenElse False _ f = f
+
+add : Nat -> Nat -> Nat
+add Zero n = n
+add (Succ m) n = Succ (add m n)
+
+const : a -> b -> a
+const x _ = x
+
+twice : {a : Type} -> (a -> a) -> a -> a
+twice f x = f (f x)
+
+loop : Nat
+loop = loop
+
+`
+
+func lower(t *testing.T, source string) core.Program {
+	t.Helper()
+	p := parser.Init(lexer.Init(util.StringSource(prelude + source)))
+	parser.Run(p)
+	if parser.Expand(p) == nil || parser.Derive(p) == nil || !parser.Analyze(p) || !parser.Check(p) {
+		t.Fatalf("unexpected failure: %v", p.Errors())
+	}
+	program, ok := parser.Lower(p)
+	if !ok {
+		t.Fatalf("unexpected failure: %v", p.Errors())
+	}
+	return program
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		strategy Strategy
+		expected string
+		fails    bool
+	}{
+		{"constant", "main : Nat\nmain = Zero", Strict, "Zero", false},
+		{"clauses", "main : Nat\nmain = ifThenElse False Zero (Succ Zero)", Strict, "Succ Zero", false},
+		{"recursion", "main : Nat\nmain = add (Succ Zero) (Succ Zero)", Strict, "Succ (Succ Zero)", false},
+		{"constructor", "main : List Nat\nmain = Cons Zero (Cons (Succ Zero) Nil)", Strict, "Cons Zero (Cons (Succ Zero) Nil)", false},
+		{"partial application", "main : Nat\nmain = twice Succ Zero", Strict, "Succ (Succ Zero)", false},
+		{"partial constructor", "main : List Nat\nmain = twice (Cons Zero) Nil", Strict, "Cons Zero (Cons Zero Nil)", false},
+		{"lambda", "main : Nat\nmain = (\\x => add x x) (Succ Zero)", Strict, "Succ (Succ Zero)", false},
+		{"let", "main : Nat\nmain = let y : Nat := Succ Zero in add y y", Strict, "Succ (Succ Zero)", false},
+		{"case", "main : Nat\nmain = case Cons Zero Nil of (\n  Nil => Succ Zero\n  Cons x _ => x\n)", Strict, "Zero", false},
+		{"where", "main : Nat\nmain = add y y where (\n  y : Nat\n  y = Succ Zero\n)", Strict, "Succ (Succ Zero)", false},
+		{"method", "main : Bool\nmain = (==) Red Blue", Strict, "False", false},
+		{"method lazy", "main : Bool\nmain = (==) Blue Blue", Lazy, "True", false},
+		{"method lazy argument", "spec Tag a where (\n  tag : Nat -> a -> Nat\n)\n\ninst Tag Color where (\n  tag _ _ = Zero\n)\n\nmain : Nat\nmain = tag loop Red", Lazy, "Zero", false},
+		{"strict argument", "main : Nat\nmain = const Zero loop", Strict, "", true},
+		{"lazy argument", "main : Nat\nmain = const Zero loop", Lazy, "Zero", false},
+		{"lazy recursion", "main : Nat\nmain = add (Succ Zero) (Succ Zero)", Lazy, "Succ (Succ Zero)", false},
+		{"nested patterns", "second : List Nat -> Nat\nsecond (Cons _ (Cons y _)) = y\nsecond _ = Zero\n\nmain : Nat\nmain = add (second (Cons Zero (Cons (Succ Zero) Nil))) (second Nil)", Lazy, "Succ Zero", false},
+		{"no match", "isTrue : Bool -> Nat\nisTrue True = Zero\n\nmain : Nat\nmain = isTrue False", Strict, "", true},
+		{"with", "isZero : Nat -> Bool\nisZero Zero = True\nisZero _ = False\n\npred : Nat -> Nat\npred n with isZero n of (\n  True => Zero\n  pred (Succ m) | False => m\n)\n\nmain : List Nat\nmain = Cons (pred Zero) (Cons (pred (Succ (Succ Zero))) Nil)", Strict, "Cons Zero (Cons (Succ Zero) Nil)", false},
+		{"with fall through", "pick : Nat -> Bool -> Nat\npick n b with b of (\n  pick Zero _ | True => Succ Zero\n  _ => Zero\n)\n\nmain : List Nat\nmain = Cons (pick Zero True) (Cons (pick (Succ Zero) True) Nil)", Lazy, "Cons (Succ Zero) (Cons Zero Nil)", false},
+		{"syntax", "syntax `if` c `then` t `else` f = ifThenElse c t f\n\nmain : Nat\nmain = if if True then False else True then Zero else Succ (if True then Zero else Zero)", Strict, "Succ Zero", false},
+		{"syntax binder", "syntax `bind` {x} `to` v `within` body = (\\x => body) v\n\nmain : Nat\nmain = bind y to Succ Zero within add y y", Strict, "Succ (Succ Zero)", false},
+		{"hygienic syntax", "syntax `double` n = let m : Nat := n in add m m\n\nmain : Nat\nmain = (\\m => double m) (Succ Zero)", Lazy, "Succ (Succ Zero)", false},
+		{"fixity", "[@infixl 6 (+)]\n(+) : Nat -> Nat -> Nat\nx + y = add x y\n\n--@infixl 7 (*)\n(*) : Nat -> Nat -> Nat\nZero * _ = Zero\nSucc m * n = n + m * n\n\ntwo : Nat\ntwo = Succ (Succ Zero)\n\nmain : Nat\nmain = Succ Zero + two * two", Strict, "Succ (Succ (Succ (Succ (Succ Zero))))", false},
+		{"right associative", "--@infixr 5 (<:)\n(<:) : Nat -> List Nat -> List Nat\nx <: xs = Cons x xs\n\nmain : List Nat\nmain = Zero <: Succ Zero <: Nil", Strict, "Cons Zero (Cons (Succ Zero) Nil)", false},
+		{"infinite loop", "main : Nat\nmain = loop", Lazy, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := New(lower(t, test.source), test.strategy)
+			v, err := in.Run("main")
+			if test.fails {
+				if err == nil {
+					t.Fatalf("expected failure, got %v", v)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			if actual := v.String(); actual != test.expected {
+				t.Errorf("expected %s, got %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestShow(t *testing.T) {
+	v, err := New(lower(t, "main : List Nat\nmain = Cons (Succ (Succ Zero)) (Cons Zero Nil)"), Lazy).Run("main")
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	tests := []struct {
+		depth    int
+		expected string
+	}{
+		{0, "Cons (Succ (Succ Zero)) (Cons Zero Nil)"},
+		{1, "Cons .. .."},
+		{2, "Cons (Succ ..) (Cons Zero Nil)"},
+		{3, "Cons (Succ (Succ Zero)) (Cons Zero Nil)"},
+	}
+
+	for _, test := range tests {
+		if actual := Show(v, test.depth); actual != test.expected {
+			t.Errorf("depth %d: expected %s, got %s", test.depth, test.expected, actual)
+		}
+	}
+}
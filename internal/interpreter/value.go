@@ -0,0 +1,204 @@
+package interpreter
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/petersalex27/yew/common/stack"
+	"github.com/petersalex27/yew/internal/core"
+)
+
+// a runtime value
+type Value interface {
+	String() string
+}
+
+// scoped bindings of names to values
+//
+// environments are never modified once a closure or thunk captures them; binding more names shares
+// the environment (see `stack.MapStack.Share`) and pushes a new scope onto the shared copy
+type Env = stack.MapStack[string, Value]
+
+type (
+	Int    int64
+	Float  float64
+	Char   rune
+	String string
+
+	// a value built by a constructor, e.g., `Cons 1 Nil`
+	Data struct {
+		Constructor core.Constructor
+		Fields      []Value
+	}
+
+	// a constructor that hasn't been given all of its arguments
+	ConstructorFunc struct{ core.Constructor }
+
+	// a function closed over the environment it was defined in
+	Closure struct {
+		// name of the function, "" for lambda abstractions
+		Name    string
+		Clauses []core.Clause
+		// decides which clause matches, nil until compiled
+		Tree core.Decision
+		env  *Env
+	}
+
+	// a spec member, dispatching to the instance for the type of its arguments
+	Method struct {
+		Name      string
+		instances []instance
+		// default implementation given by the spec, nil if there isn't one
+		fallback Value
+	}
+
+	// a function applied to fewer arguments than it takes
+	Partial struct {
+		Fun  Value
+		Args []Value
+	}
+
+	// a suspended evaluation of a term, evaluated at most once
+	Thunk struct {
+		term  core.Term
+		env   *Env
+		state thunkState
+		value Value
+	}
+)
+
+// an implementation of a spec member for the values of one type
+type instance struct {
+	typ string
+	fun Value
+}
+
+type thunkState int
+
+const (
+	suspended thunkState = iota
+	// the thunk is being evaluated--forcing it again means it depends on itself
+	forcing
+	forced
+)
+
+// returns the number of arguments a closure matches on
+func (c *Closure) Arity() int { return len(c.Clauses[0].Patterns) }
+
+func (i Int) String() string    { return strconv.FormatInt(int64(i), 10) }
+func (f Float) String() string  { return strconv.FormatFloat(float64(f), 'g', -1, 64) }
+func (c Char) String() string   { return strconv.QuoteRune(rune(c)) }
+func (s String) String() string { return strconv.Quote(string(s)) }
+
+func (d *Data) String() string { return d.show(-1) }
+
+// returns `v` as a string, printing data at most `depth` constructors deep and eliding the fields
+// below that as `..`; a `depth` of 0 prints data of any depth
+func Show(v Value, depth int) string {
+	if depth <= 0 {
+		return v.String()
+	}
+	return show(v, depth)
+}
+
+// prints data at most `depth` constructors deep, or, if `depth` is negative, of any depth
+func show(v Value, depth int) string {
+	if data, isData := forcedValue(v).(*Data); isData {
+		return data.show(depth)
+	}
+	return v.String()
+}
+
+// returns the value of `v` if it's a forced thunk, otherwise returns `v`
+func forcedValue(v Value) Value {
+	for {
+		t, isThunk := v.(*Thunk)
+		if !isThunk || t.state != forced {
+			return v
+		}
+		v = t.value
+	}
+}
+
+// constructors without fields are always printed, since eliding them wouldn't shorten anything
+func (d *Data) show(depth int) string {
+	if len(d.Fields) == 0 {
+		return d.Constructor.Name
+	}
+
+	var b strings.Builder
+	b.WriteString(d.Constructor.Name)
+	for _, field := range d.Fields {
+		b.WriteByte(' ')
+		data, isData := forcedValue(field).(*Data)
+		switch {
+		case !isData || len(data.Fields) == 0:
+			b.WriteString(show(field, depth-1))
+		case depth == 1:
+			b.WriteString("..")
+		default:
+			b.WriteString("(" + data.show(depth-1) + ")")
+		}
+	}
+	return b.String()
+}
+
+func (c ConstructorFunc) String() string { return "<constructor " + c.Name + ">" }
+
+func (c *Closure) String() string {
+	if c.Name == "" {
+		return "<function>"
+	}
+	return "<function " + c.Name + ">"
+}
+
+func (m *Method) String() string { return "<method " + m.Name + ">" }
+
+// returns true iff `m` has an instance for a particular type
+func (m *Method) hasTypedInstance() bool {
+	for _, inst := range m.instances {
+		if inst.typ != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the implementation of `m` for the type of `v`, or false if `v` is nil or its type has no
+// instance
+func (m *Method) implementationFor(v Value) (Value, bool) {
+	if ty := typeOf(v); ty != "" {
+		for _, inst := range m.instances {
+			if inst.typ == ty {
+				return inst.fun, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (p *Partial) String() string { return p.Fun.String() }
+
+func (t *Thunk) String() string {
+	if t.state == forced {
+		return t.value.String()
+	}
+	return "<thunk>"
+}
+
+// returns the name of the type of a (forced) value, or "" if the value isn't data or a literal
+func typeOf(v Value) string {
+	switch x := v.(type) {
+	case Int:
+		return "Int"
+	case Float:
+		return "Float"
+	case Char:
+		return "Char"
+	case String:
+		return "String"
+	case *Data:
+		return x.Constructor.Type
+	}
+	return ""
+}
@@ -77,6 +77,14 @@ var commands = map[string]token.Type{
 	":run":       token.Run_c,
 	":r":         token.Run_c,
 	":set":       token.Set_c,
+	":kind":      token.Kind_c,
+	":k":         token.Kind_c,
+	":api":       token.Api_c,
+	":save":      token.Save_c,
+	":restore":   token.Restore_c,
+	":begin":     token.Begin_c,
+	":end":       token.End_c,
+	":include":   token.Include_c,
 }
 
 func (lex *Lexer) isKeyword(s string) (token.Type, bool) {
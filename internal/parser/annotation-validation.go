@@ -0,0 +1,221 @@
+// =================================================================================================
+// annotation validation: checks each annotation against the registry of known annotations, i.e.,
+// that it's known, that it targets a node it may target, and that its arguments match its
+// parameters
+// =================================================================================================
+
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/api/annotate"
+	"github.com/petersalex27/yew/api/token"
+	"github.com/petersalex27/yew/common/data"
+	"github.com/petersalex27/yew/internal/errors"
+)
+
+type annotationValidator struct {
+	reporter
+	registry *annotate.Registry
+}
+
+// validates the annotations of the meta section, imports, body elements (and their members,
+// constructors, and where clauses), and footer of `ps` against the default registry
+//
+// unknown annotations are warned about; misplaced annotations and bad arguments are errors
+func validateAnnotations(ps *ParserState) {
+	v := &annotationValidator{reporter: reporter{ps, errors.Syntax}, registry: annotate.Default}
+	if h, just := ps.ast.header.Break(); just {
+		if m, just := h.Fst().Break(); just {
+			v.validate(m.annotations, annotate.OnModule)
+		}
+		for _, statement := range h.Snd().Elements() {
+			v.validate(statement.Fst(), annotate.OnImport)
+		}
+	}
+
+	if b, just := ps.ast.body.Break(); just {
+		for _, elem := range bodyElements(b) {
+			v.mainElement(elem)
+		}
+	}
+	v.validate(ps.ast.footer.Maybe, annotate.OnFooter)
+}
+
+func (v *annotationValidator) mainElement(elem mainElement) {
+	switch e := elem.(type) {
+	case def:
+		v.validate(e.annotations, annotate.OnDef)
+		v.defBody(e.defBody)
+	case typing:
+		v.validate(e.annotations, annotate.OnTyping)
+	case typeDef:
+		v.validate(e.annotations, annotate.OnTypeDef)
+		if constructors, _, isImpossible := e.typedef.Snd().Break(); !isImpossible {
+			for _, constructor := range constructors.Elements() {
+				v.validate(constructor.annotations, annotate.OnConstructor)
+			}
+		}
+	case typeAlias:
+		v.validate(e.annotations, annotate.OnAlias)
+	case specDef:
+		v.validate(e.annotations, annotate.OnSpec)
+		v.members(e.specBody)
+	case specInst:
+		v.validate(e.annotations, annotate.OnInst)
+		v.members(e.body)
+	case syntax:
+		v.validate(e.annotations, annotate.OnSyntax)
+	}
+}
+
+func (v *annotationValidator) members(body specBody) {
+	for _, member := range body.Elements() {
+		v.mainElement(data.Cases(member, (def).asMainElement, (typing).asMainElement))
+	}
+}
+
+func (v *annotationValidator) defBody(body defBody) {
+	if _, possible, isPossible := body.Break(); isPossible {
+		if where, just := possible.Snd().Break(); just {
+			for _, elem := range where.Elements() {
+				v.mainElement(elem)
+			}
+		}
+	}
+}
+
+// validates each annotation in `as`, which annotate a node of kind `target`
+func (v *annotationValidator) validate(as data.Maybe[annotations], target annotate.Target) {
+	annots, just := as.Break()
+	if !just {
+		return
+	}
+
+	for _, annot := range annots.Elements() {
+		name, args, bad := annotationArgs(annot)
+		spec, found := v.registry.Lookup(name)
+		if !found {
+			v.warningAt(UnknownAnnotation+": `"+name+"`", annot)
+			continue
+		}
+		if spec.Targets&target == 0 {
+			v.errorAt(MisplacedAnnotation+": `"+name+"` may only target "+spec.Targets.String(), annot)
+			continue
+		}
+		if bad != "" {
+			v.errorAt(BadAnnotationArguments+": unrecognized argument `"+bad+"`", annot)
+		} else if err := spec.Check(args); err != nil {
+			v.errorAt(BadAnnotationArguments+": "+err.Error(), annot)
+		}
+	}
+}
+
+// returns the name and arguments of `annot`; if an argument is unrecognizable, it's returned as
+// `bad`
+func annotationArgs(annot annotation) (name string, args []annotate.Arg, bad string) {
+	flat, enclosed, isEnclosed := annot.Break()
+	if !isEnclosed {
+		words := annotationWords(flat.Extract().String())
+		for _, word := range words[1:] {
+			arg, ok := wordArg(word)
+			if !ok {
+				return words[0], nil, word
+			}
+			args = append(args, arg)
+		}
+		return words[0], args, ""
+	}
+
+	name = nameString(identAsName(enclosed.Fst()))
+	for _, node := range enclosed.Snd().Elements() {
+		arg, ok := tokenArg(node)
+		if !ok {
+			return name, nil, arg.Value
+		}
+		args = append(args, arg)
+	}
+	return name, args, ""
+}
+
+// splits the text of a flat annotation into words, keeping quoted strings whole
+func annotationWords(text string) (words []string) {
+	for text = strings.TrimSpace(text); text != ""; text = strings.TrimSpace(text) {
+		end := strings.IndexFunc(text, unicode.IsSpace)
+		if text[0] == '"' {
+			end = quoteEnd(text)
+		}
+		if end < 0 {
+			end = len(text)
+		}
+		words, text = append(words, text[:end]), text[end:]
+	}
+	return words
+}
+
+// returns the index just past the closing quote of the string starting `text`, or -1 if the
+// string is unterminated
+func quoteEnd(text string) int {
+	for i := 1; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+		} else if text[i] == '"' {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// classifies a word of a flat annotation
+func wordArg(word string) (annotate.Arg, bool) {
+	if isDigits(word) {
+		return annotate.Arg{Kind: annotate.Int, Value: word}, true
+	}
+	if len(word) >= 2 && word[0] == '"' && word[len(word)-1] == '"' {
+		value, err := strconv.Unquote(word)
+		return annotate.Arg{Kind: annotate.String, Value: value}, err == nil
+	}
+	if op, found := strings.CutPrefix(word, "("); found {
+		op, closed := strings.CutSuffix(op, ")")
+		return annotate.Arg{Kind: annotate.Operator, Value: op}, closed && op != ""
+	}
+	return annotate.Arg{Kind: annotate.Name, Value: word}, isIdentifier(word)
+}
+
+// classifies a token of an enclosed annotation; the value of an unrecognized token is its text
+func tokenArg(node api.Node) (annotate.Arg, bool) {
+	tok, isToken := node.(api.Token)
+	if !isToken { // enclosed annotations only hold tokens
+		return annotate.Arg{Value: "?"}, false
+	}
+
+	value := tok.String()
+	switch {
+	case token.IntValue.Match(tok):
+		return annotate.Arg{Kind: annotate.Int, Value: value}, isDigits(value)
+	case token.StringValue.Match(tok), token.ImportPath.Match(tok): // a single word is scanned as an import path, e.g., "old"
+		return annotate.Arg{Kind: annotate.String, Value: value}, true
+	case token.Infix.Match(tok):
+		return annotate.Arg{Kind: annotate.Operator, Value: value}, true
+	case token.Id.Match(tok):
+		return annotate.Arg{Kind: annotate.Name, Value: value}, isIdentifier(value)
+	}
+	return annotate.Arg{Value: value}, false
+}
+
+func isDigits(s string) bool {
+	return s != "" && strings.TrimLeft(s, "0123456789") == ""
+}
+
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !(unicode.IsLetter(r) || r == '_' || (i > 0 && (unicode.IsDigit(r) || r == '\''))) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,46 @@
+//go:build test
+// +build test
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/petersalex27/yew/api/annotate"
+)
+
+func TestValidateAnnotations(t *testing.T) {
+	if err := annotate.Register(annotate.Spec{Name: "memo", Targets: annotate.OnDef, Params: []annotate.Kind{annotate.Int}, Optional: 1}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { annotate.Unregister("memo") })
+
+	tests := []struct {
+		name     string
+		source   string
+		expected []report
+	}{
+		{"inline", "[@inline]\nf : Nat\nf = Zero", nil},
+		{"flat", "f : Nat\n--@inline\nf = Zero", nil},
+		{"deprecated", "[@deprecated]\nf : Nat\nf = Zero", nil},
+		{"deprecated message", "--@deprecated \"use g instead\"\nf : Nat\nf = Zero", nil},
+		{"deprecated word", "[@deprecated \"old\"]\nf : Nat\nf = Zero", nil},
+		{"deprecated path", "[@deprecated \"a/b\"]\nf : Nat\nf = Zero", nil},
+		{"flat deprecated word", "--@deprecated \"old\"\nf : Nat\nf = Zero", nil},
+		{"constructor", "Unit : Type where (\n  [@deprecated \"use Zero\"]\n  U : Unit\n)", nil},
+		{"custom", "f : Nat\n[@memo 3]\nf = Zero", nil},
+		{"unknown", "[@frobnicate 1 2]\nf : Nat\nf = Zero", []report{warning(UnknownAnnotation + ": `frobnicate`")}},
+		{"misplaced", "[@inline]\nUnit : Type where (\n  U : Unit\n)", []report{syntaxError(MisplacedAnnotation + ": `inline` may only target definitions or typings")}},
+		{"misplaced custom", "[@memo]\nf : Nat\nf = Zero", []report{syntaxError(MisplacedAnnotation + ": `memo` may only target definitions")}},
+		{"too few arguments", "[@infixl 6]\nf : Nat\nf = Zero", []report{syntaxError(BadAnnotationArguments + ": `infixl` takes at least 2 argument(s), got 1")}},
+		{"too many arguments", "[@inline 1]\nf : Nat\nf = Zero", []report{syntaxError(BadAnnotationArguments + ": `inline` takes at most 0 argument(s), got 1")}},
+		{"wrong argument", "--@deprecated g\nf : Nat\nf = Zero", []report{syntaxError(BadAnnotationArguments + ": argument 1 of `deprecated` must be a string, got a name")}},
+		{"unrecognized argument", "--@infixl 6 +\nf : Nat\nf = Zero", []report{syntaxError(BadAnnotationArguments + ": unrecognized argument `+`")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectReports(t, checkSource(t, typeCheckPrelude+test.source), test.expected...)
+		})
+	}
+}
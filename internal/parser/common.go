@@ -87,6 +87,29 @@ func lookahead1(p parser, types ...token.Type) bool {
 	return false
 }
 
+// returns true iff the current token opens a parenthesized typing, e.g., `(x : A)`, `(x, y : A)`, or
+// `(once x : A)`
+func lookaheadEnclosedTyping(p parser) bool {
+	ps, ok := p.(*ParserState)
+	if !ok || !token.LeftParen.Match(p.current()) {
+		return false
+	}
+
+	origin := ps.tokenCounter // capture the current token counter for restoration
+	defer func() { ps.tokenCounter = origin }()
+
+	ps.advance()
+	ps.dropNewlines()
+	if lookahead1(p, token.Erase, token.Once) {
+		return true
+	} else if !token.Id.Match(p.current()) {
+		return false
+	}
+	ps.advance()
+	ps.dropNewlines()
+	return lookahead1(p, token.Colon, token.Comma)
+}
+
 // performs a lookahead 2-ish. If the current token matches the first type in the pair, then it will try to
 // match the second type in the pair, dropping newlines in between the two
 func lookahead2(p parser, types ...[2]token.Type) bool {
@@ -207,7 +230,11 @@ func getKeywordAtCurrent(p parser, keyword token.Type, dropBits dropNewlineBits)
 func writeErrors(p parser, es data.Ers) parser {
 	var out parser = p
 	for _, e := range es.Elements() {
-		out = p.report(parseError(p, e), e.Fatal())
+		if !e.Fatal() {
+			p.warn(parseWarning(p, e))
+			continue
+		}
+		out = p.report(parseError(p, e), true)
 	}
 	return out
 }
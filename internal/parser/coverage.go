@@ -0,0 +1,338 @@
+// =================================================================================================
+// coverage checking: warns on clauses that don't cover every argument and clauses never reached
+// =================================================================================================
+
+package parser
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/internal/core"
+	"github.com/petersalex27/yew/internal/symbol"
+)
+
+// a clause whose coverage is checked
+type coverClause struct {
+	patterns []core.Pattern
+	// true iff the clause is marked `impossible`
+	impossible bool
+	at         api.Positioned
+}
+
+// records the constructors of a type definition, in the order they're defined
+func (c *typeChecker) declareConstructors(td typeDef) {
+	ty := nameString(td.typedef.Fst().typing.Fst())
+	c.signatures[ty] = []string{}
+	constructors, _, isImpossible := td.typedef.Snd().Break()
+	if isImpossible {
+		return
+	}
+
+	for _, constructor := range constructors.Elements() {
+		n, conTy := constructor.constructor.Split()
+		c.constructors[nameString(n)] = core.Constructor{Name: nameString(n), Type: ty, Arity: len(constructorFields(conTy))}
+		c.signatures[ty] = append(c.signatures[ty], nameString(n))
+	}
+}
+
+// checks the coverage of each function defined by the definitions in `elems`
+func (c *typeChecker) coverDefs(elems []mainElement) {
+	index := make(map[string]int)
+	var names []name
+	var groups [][]coverClause
+	for _, elem := range elems {
+		d, isDef := elem.(def)
+		if !isDef {
+			continue
+		}
+		n, params, ok := definedName(d.pattern)
+		if !ok {
+			continue
+		}
+
+		i, found := index[nameString(n)]
+		if !found {
+			i = len(groups)
+			index[nameString(n)] = i
+			names, groups = append(names, n), append(groups, nil)
+		}
+		var patterns []core.Pattern
+		for _, param := range params {
+			if enclosed, isEnclosed := param.(patternEnclosed); !isEnclosed || !enclosed.implicit {
+				patterns = append(patterns, lowerPattern(param))
+			}
+		}
+		_, _, possible := d.defBody.Break()
+		groups[i] = append(groups[i], coverClause{patterns, !possible, d.pattern})
+	}
+
+	for i, n := range names {
+		var domains []api.Type
+		if ty, found := c.types.Lookup(nameString(n)); found {
+			domains = explicitDomains(ty)
+		}
+		head := nameString(n)
+		if isInfixName(n) {
+			head = "(" + head + ")"
+		}
+		c.cover(groups[i][0].at, groups[i], domains, func(args []*example) string {
+			return (&example{head: head, args: args}).String()
+		})
+	}
+}
+
+// checks the coverage of the arms of a case expression
+func (c *typeChecker) coverArms(x caseExpr, scrutinee api.Type) {
+	var clauses []coverClause
+	for _, arm := range x.Snd().Elements() {
+		_, _, possible := arm.Snd().Break()
+		clauses = append(clauses, coverClause{[]core.Pattern{lowerPattern(arm.Fst())}, !possible, arm.Fst()})
+	}
+	c.cover(x.Fst(), clauses, []api.Type{scrutinee}, func(args []*example) string { return args[0].String() })
+}
+
+// checks the coverage of the arms of a with clause refining `lhs`; like the function the clause is
+// lowered into (see `lowerWith`), each arm matches its refinement of each variable of `lhs` and then
+// the scrutinee
+func (c *typeChecker) coverWith(w withClause, lhs refinable, scrutinee api.Type) {
+	vars := refinableVariables(lhs)
+	var clauses []coverClause
+	for _, arm := range w.Snd().Elements() {
+		_, pat, _ := withArm(arm)
+		_, _, possible := arm.Snd().Break()
+		clauses = append(clauses, coverClause{withArmPatterns(arm, lhs, vars), !possible, pat})
+	}
+
+	domains := append(make([]api.Type, len(vars)), scrutinee)
+	c.cover(w.Fst(), clauses, domains, func(args []*example) string {
+		refinements, last := args[:len(args)-1], args[len(args)-1]
+		if slices.ContainsFunc(refinements, func(e *example) bool { return e != nil }) {
+			return strings.TrimSpace((&example{args: refinements}).String()) + " | " + last.String()
+		}
+		return last.String()
+	})
+}
+
+// returns the domains of the explicit parameters of `ty`
+func explicitDomains(ty api.Type) (domains []api.Type) {
+	for {
+		pi, isPi := ty.(symbol.Pi)
+		if !isPi {
+			return domains
+		}
+		if !pi.Implicit() && !pi.Constraint() {
+			domains = append(domains, pi.Domain())
+		}
+		ty = pi.Target()
+	}
+}
+
+// warns, at `at`, if `clauses` don't match every argument, and, at each clause, if the clause is
+// never reached or is marked `impossible` but can be matched
+//
+// `domains` holds the type of each argument (missing or nil when unknown), and `format` writes the
+// arguments no clause matches as source
+func (c *typeChecker) cover(at api.Positioned, clauses []coverClause, domains []api.Type, format func([]*example) string) {
+	if len(clauses) == 0 {
+		return
+	} else if len(clauses[0].patterns) == 0 { // the first clause matches, so no other can be reached
+		for _, clause := range clauses[1:] {
+			if len(clause.patterns) == 0 {
+				c.warningAt(UnreachableClause, clause.at)
+			}
+		}
+		return
+	}
+
+	var covering []core.Clause
+	var positions []api.Positioned
+	for _, clause := range clauses {
+		if len(clause.patterns) != len(clauses[0].patterns) {
+			return // reported by the type checker
+		}
+		if clause.impossible && !c.uninhabited(clause.patterns, domains) {
+			c.warningAt(ReachableImpossibleClause, clause.at)
+			continue
+		}
+		covering = append(covering, core.Clause{Patterns: clause.patterns})
+		positions = append(positions, clause.at)
+	}
+
+	tree := core.Compile(covering, c.constructors)
+	if missing, found := c.missing(tree, make([]*example, len(clauses[0].patterns))); found {
+		c.warningAt(NonExhaustivePatterns+": missing `"+format(missing)+"`", at)
+	}
+
+	reached := make(map[int]bool)
+	leaves(tree, reached)
+	for i := range covering {
+		if !reached[i] {
+			c.warningAt(UnreachableClause, positions[i])
+		}
+	}
+}
+
+// records the clause of each leaf of a decision tree
+func leaves(tree core.Decision, reached map[int]bool) {
+	switch d := tree.(type) {
+	case core.Leaf:
+		reached[d.Clause] = true
+	case core.Switch:
+		for _, branch := range d.Constructors {
+			leaves(branch.Next, reached)
+		}
+		for _, branch := range d.Literals {
+			leaves(branch.Next, reached)
+		}
+		if d.Default != nil {
+			leaves(d.Default, reached)
+		}
+	}
+}
+
+// returns true iff some pattern of a clause can't match any value of its argument's type
+func (c *typeChecker) uninhabited(patterns []core.Pattern, domains []api.Type) bool {
+	for i, pat := range patterns {
+		if i < len(domains) && c.uninhabitedPattern(pat, domains[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *typeChecker) uninhabitedPattern(pat core.Pattern, ty api.Type) bool {
+	switch p := pat.(type) {
+	case core.PVar, core.PWild, core.PAccess:
+		return c.empty(ty)
+	case core.PCon:
+		conTy, found := c.types.Lookup(p.Name)
+		if !found {
+			return false
+		}
+		return c.uninhabited(p.Args, explicitDomains(conTy))
+	}
+	return false
+}
+
+// returns true iff `ty` is a type defined without constructors, e.g., `Void : Type where impossible`
+func (c *typeChecker) empty(ty api.Type) bool {
+	if ty == nil {
+		return false
+	}
+	ty = c.unifier.Apply(ty)
+	if _, isPi := ty.(symbol.Pi); isPi {
+		return false
+	} else if _, isVar := symbol.IsVariable(ty); isVar {
+		return false
+	}
+	head, _ := ty.Break()
+	constructors, found := c.signatures[head]
+	return found && len(constructors) == 0
+}
+
+// an argument no clause matches, e.g., `Succ _`; nil stands for any value
+type example struct {
+	head string
+	args []*example
+}
+
+func (e *example) String() string {
+	if e == nil {
+		return "_"
+	}
+
+	var b strings.Builder
+	b.WriteString(e.head)
+	for _, arg := range e.args {
+		if arg != nil && len(arg.args) != 0 {
+			b.WriteString(" (" + arg.String() + ")")
+		} else {
+			b.WriteString(" " + arg.String())
+		}
+	}
+	return b.String()
+}
+
+// returns the value at `occurrence` within `args`
+func exampleAt(args []*example, occurrence core.Occurrence) **example {
+	at := &args[occurrence[0]]
+	for _, field := range occurrence[1:] {
+		at = &(*at).args[field]
+	}
+	return at
+}
+
+func (e *example) clone() *example {
+	if e == nil {
+		return nil
+	}
+	args := make([]*example, len(e.args))
+	for i, arg := range e.args {
+		args[i] = arg.clone()
+	}
+	return &example{head: e.head, args: args}
+}
+
+func constructorExample(name string, arity int) *example {
+	return &example{head: name, args: make([]*example, arity)}
+}
+
+// returns the arguments of the first path through `tree` that ends without a matching clause
+func (c *typeChecker) missing(tree core.Decision, args []*example) ([]*example, bool) {
+	switch d := tree.(type) {
+	case core.Unmatched:
+		missing := make([]*example, len(args))
+		for i, arg := range args {
+			missing[i] = arg.clone()
+		}
+		return missing, true
+	case core.Switch:
+		at := exampleAt(args, d.Occurrence)
+		defer func() { *at = nil }()
+		for _, branch := range d.Constructors {
+			*at = constructorExample(branch.Name, branch.Arity)
+			if missing, found := c.missing(branch.Next, args); found {
+				return missing, true
+			}
+		}
+		for _, branch := range d.Literals {
+			*at = &example{head: literalString(branch.Lit)}
+			if missing, found := c.missing(branch.Next, args); found {
+				return missing, true
+			}
+		}
+		if d.Default == nil {
+			return nil, false
+		}
+
+		*at = nil
+		if len(d.Constructors) != 0 {
+			// stand for the values the default is taken for with a constructor no branch tests
+			ty := c.constructors[d.Constructors[0].Name].Type
+			for _, name := range c.signatures[ty] {
+				if !slices.ContainsFunc(d.Constructors, func(b core.ConstructorBranch) bool { return b.Name == name }) {
+					*at = constructorExample(name, c.constructors[name].Arity)
+					break
+				}
+			}
+		}
+		return c.missing(d.Default, args)
+	}
+	return nil, false
+}
+
+// returns a literal as written in source
+func literalString(lit core.Lit) string {
+	switch lit.Kind {
+	case core.CharLit:
+		r, _ := utf8.DecodeRuneInString(lit.Value)
+		return strconv.QuoteRune(r)
+	case core.StringLit:
+		return strconv.Quote(lit.Value)
+	}
+	return lit.Value
+}
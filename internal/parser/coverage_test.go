@@ -0,0 +1,43 @@
+//go:build test
+// +build test
+
+package parser
+
+import "testing"
+
+const coveragePrelude = `Void : Type where impossible
+
+Box : Type where (
+  MkBox : Void -> Box
+)
+
+`
+
+func TestCoverage(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []report
+	}{
+		{"exhaustive", "not : Bool -> Bool\nnot True = False\nnot False = True", nil},
+		{"missing constructor", "isZero : Nat -> Bool\nisZero Zero = True", []report{warning(NonExhaustivePatterns + ": missing `isZero (Succ _)`")}},
+		{"missing nested", "second : List Nat -> Nat\nsecond (Cons _ (Cons x _)) = x\nsecond Nil = Zero", []report{warning(NonExhaustivePatterns + ": missing `second (Cons _ Nil)`")}},
+		{"missing second argument", "and : Bool -> Bool -> Bool\nand True True = True\nand False _ = False", []report{warning(NonExhaustivePatterns + ": missing `and True False`")}},
+		{"wildcard", "isZero : Nat -> Bool\nisZero Zero = True\nisZero _ = False", nil},
+		{"unreachable", "isZero : Nat -> Bool\nisZero _ = False\nisZero Zero = True", []report{warning(UnreachableClause)}},
+		{"unreachable duplicate", "not : Bool -> Bool\nnot True = False\nnot False = True\nnot True = True", []report{warning(UnreachableClause)}},
+		{"case", "f : Nat -> Nat\nf n = case n of (\n  Zero => Zero\n)", []report{warning(NonExhaustivePatterns + ": missing `Succ _`")}},
+		{"case exhaustive", "f : Nat -> Nat\nf n = case n of (\n  Zero => Zero\n  Succ m => m\n)", nil},
+		{"impossible empty type", "absurd : Void -> Nat\nabsurd x impossible", nil},
+		{"impossible field", "unbox : Box -> Nat\nunbox (MkBox v) impossible", nil},
+		{"impossible case", "f : Void -> Nat\nf v = case v of (\n  x impossible\n)", nil},
+		{"reachable impossible", "f : Nat -> Nat\nf Zero = Zero\nf (Succ n) impossible", []report{warning(ReachableImpossibleClause), warning(NonExhaustivePatterns + ": missing `f (Succ _)`")}},
+		{"where", "f : Nat\nf = g Zero where (\n  g : Nat -> Nat\n  g Zero = Zero\n)", []report{warning(NonExhaustivePatterns + ": missing `g (Succ _)`")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectReports(t, checkSource(t, typeCheckPrelude+coveragePrelude+test.source), test.expected...)
+		})
+	}
+}
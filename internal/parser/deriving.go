@@ -0,0 +1,473 @@
+// =================================================================================================
+// deriving: synthesizes the spec instances requested by the deriving clauses of type definitions
+// =================================================================================================
+
+package parser
+
+import (
+	"strconv"
+
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/api/token"
+	"github.com/petersalex27/yew/common/data"
+	"github.com/petersalex27/yew/internal/errors"
+)
+
+// a constructor of a type deriving instances
+type derivedConstructor struct {
+	name name
+	// types of the explicit arguments of the constructor, e.g., `a` and `List a` for
+	// `Cons : a -> List a -> List a`
+	fields []typ
+}
+
+// synthesizes the members of a derived instance from the constructors of the type
+type deriver = func(g generator, cons []derivedConstructor) []def
+
+// specs that can be derived
+//
+// derived members only refer to the names below, which must be in scope wherever the instance is
+// derived:
+//   - Eq: `(==) : a -> a -> Bool`, built from `True` and `False`
+//   - Ord: `compare : a -> a -> Ordering`, built from `LT`, `EQ`, and `GT`
+//   - Show: `show : a -> String`, built from string literals and `(++)`
+var derivers = map[string]deriver{
+	"Eq":   deriveEq,
+	"Ord":  deriveOrd,
+	"Show": deriveShow,
+}
+
+type derivingState struct {
+	reporter
+}
+
+// adds an instance to the body of `ps` for each spec in the deriving clauses of its type definitions;
+// each instance directly follows the type definition that derives it
+func deriveInstances(ps *ParserState) {
+	b, just := ps.ast.body.Break()
+	if !just {
+		return
+	}
+
+	d := &derivingState{reporter: reporter{ps, errors.Type}}
+	elems := data.Nil[bodyElement](b.Len())
+	for _, elem := range b.Elements() {
+		elems = elems.Snoc(elem)
+		_, visible, isVisible := elem.Break()
+		if td, isTypeDef := visible.(typeDef); isVisible && isTypeDef {
+			for _, inst := range d.typeDef(td) {
+				elems = elems.Snoc(inst.setVisibility(td.visibility).asBodyElement())
+			}
+		}
+	}
+	ps.ast.body = data.Just(body{elems})
+}
+
+// returns the instances derived by `td`
+func (d *derivingState) typeDef(td typeDef) []specInst {
+	clause, just := td.deriving.Break()
+	if !just {
+		return nil
+	}
+
+	ty := td.typedef.Fst().typing.Fst()
+	constructors, _, isImpossible := td.typedef.Snd().Break()
+	if isImpossible {
+		d.errorWithNote(UnsupportedDerivingType+": `"+nameString(ty)+"`", clause, "type is defined here", ty)
+		return nil
+	}
+
+	cons := make([]derivedConstructor, 0, constructors.Len())
+	for _, constructor := range constructors.Elements() {
+		n, conTy := constructor.constructor.Split()
+		cons = append(cons, derivedConstructor{n, constructorFields(conTy)})
+	}
+
+	var out []specInst
+	for _, cr := range clause.Elements() {
+		if inst, ok := d.derive(ty, cons, cr); ok {
+			out = append(out, inst)
+		}
+	}
+	return out
+}
+
+// derives the instance requested by `cr` for the type `ty`
+func (d *derivingState) derive(ty name, cons []derivedConstructor, cr constrainer) (inst specInst, ok bool) {
+	spec := nameString(name(cr.Fst()))
+	members, supported := derivers[spec]
+	if !supported {
+		d.errorAt(UnsupportedDeriving+": `"+spec+"`", cr)
+		return inst, false
+	}
+
+	g := generator{cr.GetPos()}
+	target, ok := g.instanceTarget(ty, cr.Snd())
+	if !ok {
+		d.errorWithNote(IllegalDerivingTarget+": `"+nameString(ty)+"`", cr, "type is defined here", ty)
+		return inst, false
+	}
+
+	for _, con := range cons {
+		for _, field := range con.fields {
+			if isFunctionType(field) {
+				d.errorWithNote(UnsupportedDerivingConstructor+": `"+nameString(con.name)+"`", cr, "constructor has a function as an argument", field)
+				return inst, false
+			}
+		}
+	}
+
+	head := data.EMakePair[specHead](g.context(cr.Fst(), target, cons), data.EMakePair[constrainer](cr.Fst(), target))
+	defs := members(g, cons)
+	elems := make([]specMember, len(defs))
+	for i, member := range defs {
+		elems[i] = data.Inl[typing](member)
+	}
+	return makeSpecInst(head, data.Nothing[constrainer](cr), specBody{data.Construct(elems[0], elems[1:]...)}), true
+}
+
+// returns the types of the explicit arguments of a constructor's type
+func constructorFields(ty typ) (fields []typ) {
+	for {
+		switch x := ty.(type) {
+		case functionType:
+			fields = append(fields, parameterTypes(x.Fst())...)
+			ty = x.Snd()
+		case forallType:
+			ty = x.Snd()
+		case constrainedType:
+			ty = x.Snd()
+		default:
+			return fields
+		}
+	}
+}
+
+// returns the type of each explicit parameter bound by the domain of a function type, e.g., `A` and
+// `A` for `(x, y : A)`
+func parameterTypes(domain typ) []typ {
+	enclosed, isEnclosed := domain.(enclosedType)
+	if !isEnclosed {
+		return []typ{domain}
+	} else if enclosed.implicit {
+		return nil
+	}
+
+	inner, isTyping := enclosed.typ.(innerTyping)
+	if !isTyping {
+		return []typ{enclosed.typ}
+	}
+	fields := make([]typ, inner.typing.Fst().Len())
+	for i := range fields {
+		fields[i] = inner.typing.Snd()
+	}
+	return fields
+}
+
+// returns true iff `ty` is the type of a function
+func isFunctionType(ty typ) bool {
+	switch x := ty.(type) {
+	case functionType:
+		return true
+	case enclosedType:
+		return isFunctionType(x.typ)
+	case forallType:
+		return isFunctionType(x.Snd())
+	case constrainedType:
+		return isFunctionType(x.Snd())
+	}
+	return false
+}
+
+// adds each type variable of `ty` to `vars`
+func typeVariables(ty typ, vars map[string]bool) {
+	switch x := ty.(type) {
+	case name:
+		if isLowerName(x) {
+			vars[nameString(x)] = true
+		}
+	case appType:
+		typeVariables(x.Fst(), vars)
+		for _, arg := range x.Snd().Elements() {
+			typeVariables(arg, vars)
+		}
+	case functionType:
+		typeVariables(x.Fst(), vars)
+		typeVariables(x.Snd(), vars)
+	case enclosedType:
+		typeVariables(x.typ, vars)
+	case innerTypeTerms:
+		for _, term := range x.Elements() {
+			typeVariables(term, vars)
+		}
+	case innerTyping:
+		typeVariables(x.typing.Snd(), vars)
+	case forallType:
+		typeVariables(x.Snd(), vars)
+	case constrainedType:
+		typeVariables(x.Snd(), vars)
+	}
+}
+
+// returns true iff `pat` is `ty`, possibly applied to arguments, e.g., `List a` for `List`
+func isTypeApplication(ty name, pat pattern) bool {
+	switch p := pat.(type) {
+	case name:
+		return nameString(p) == nameString(ty)
+	case patternApp:
+		return isTypeApplication(ty, p.Fst())
+	case patternEnclosed:
+		return p.Len() == 1 && !p.implicit && isTypeApplication(ty, p.Head())
+	}
+	return false
+}
+
+// returns the lowercase names of `pat` if `pat` is only lowercase names, e.g., `a b`
+func patternVariables(pat pattern) (vars []name, ok bool) {
+	switch p := pat.(type) {
+	case name:
+		return []name{p}, isLowerName(p)
+	case patternApp:
+		for _, elem := range append([]pattern{p.Fst()}, p.Snd().Elements()...) {
+			n, isName := elem.(name)
+			if !isName || !isLowerName(n) {
+				return nil, false
+			}
+			vars = append(vars, n)
+		}
+		return vars, true
+	}
+	return nil, false
+}
+
+// builds the nodes of derived instances; every token it creates is placed at the constrainer of the
+// deriving clause, so errors in a derived instance point at the part of the clause that derived it
+type generator struct{ at api.Position }
+
+func (g generator) token(typ token.Type, value string) api.Token {
+	start, end := g.at.Pos()
+	return token.Token{Value: value, Typ: typ, Start: start, End: end}
+}
+
+func (g generator) name(x string) name {
+	if n := data.EOne[name](g.token(token.Id, x)); !isInfixName(n) {
+		return n
+	}
+	return data.EOne[name](g.token(token.Infix, x))
+}
+
+func (g generator) string(s string) literal {
+	return data.EOne[literal](g.token(token.StringValue, s))
+}
+
+func (g generator) wildcard() pattern {
+	return data.EOne[wildcard](g.token(token.Underscore, "_"))
+}
+
+// returns `n` names with the prefix `x`, e.g., `x'1`, `x'2`, ..
+func (g generator) variables(x string, n int) []name {
+	vars := make([]name, n)
+	for i := range vars {
+		vars[i] = g.name(x + "'" + strconv.Itoa(i+1))
+	}
+	return vars
+}
+
+func (g generator) app(head expr, args ...expr) expr {
+	return data.EMakePair[exprApp](head, data.Construct(args[0], args[1:]...))
+}
+
+func (g generator) patternApp(head pattern, args ...pattern) pattern {
+	return data.EMakePair[patternApp](head, data.Construct(args[0], args[1:]...))
+}
+
+func (g generator) enclosed(pat pattern) pattern {
+	return patternEnclosed{NonEmpty: data.Construct(pat)}
+}
+
+func (g generator) caseOf(scrutinee pattern, arms ...caseArm) expr {
+	return data.EMakePair[caseExpr](scrutinee, caseArms{data.Construct(arms[0], arms[1:]...)})
+}
+
+func (g generator) arm(pat pattern, e expr) caseArm {
+	return data.EMakePair[caseArm](pat, g.body(e))
+}
+
+func (g generator) body(e expr) defBody {
+	return data.EInr[defBody](data.EMakePair[defBodyPossible](data.Inr[withClause](e), data.Nothing[whereClause](g.at)))
+}
+
+func (g generator) def(pat pattern, e expr) def {
+	return makeDef(pat, g.body(e))
+}
+
+// returns the pattern a constructor is matched by, e.g., `(Cons x'1 x'2)`
+func (g generator) constructor(con derivedConstructor, vars []name) pattern {
+	if len(vars) == 0 {
+		return con.name
+	}
+	args := make([]pattern, len(vars))
+	for i, v := range vars {
+		args[i] = v
+	}
+	return g.enclosed(g.patternApp(con.name, args...))
+}
+
+// returns a pattern matching any value built by a constructor, e.g., `(Cons _ _)`
+func (g generator) anyOf(con derivedConstructor) pattern {
+	if len(con.fields) == 0 {
+		return con.name
+	}
+	args := make([]pattern, len(con.fields))
+	for i := range args {
+		args[i] = g.wildcard()
+	}
+	return g.enclosed(g.patternApp(con.name, args...))
+}
+
+// returns the pattern of the type an instance is derived for: `pat` when it names `ty`, e.g.,
+// `List a` in `deriving Eq (List a)`, otherwise `ty` applied to `pat`'s variables, e.g., `List a` in
+// `deriving Eq a`
+func (g generator) instanceTarget(ty name, pat pattern) (pattern, bool) {
+	if isTypeApplication(ty, pat) {
+		return pat, true
+	}
+	vars, ok := patternVariables(pat)
+	if !ok {
+		return nil, false
+	}
+	args := make([]pattern, len(vars))
+	for i, v := range vars {
+		args[i] = v
+	}
+	return g.enclosed(g.patternApp(ty, args...)), true
+}
+
+// returns the constraints a derived instance depends on: the spec applied to each type variable of
+// the target that some constructor holds a value of, e.g., `Eq a` for `Eq (List a)`
+func (g generator) context(spec upperIdent, target pattern, cons []derivedConstructor) data.Maybe[constraintVerified] {
+	held := make(map[string]bool)
+	for _, con := range cons {
+		for _, field := range con.fields {
+			typeVariables(field, held)
+		}
+	}
+
+	var elems []constraintElem
+	seen := make(map[string]bool)
+	for _, v := range nameVariables(target) {
+		if x := nameString(v); held[x] && !seen[x] {
+			seen[x] = true
+			elems = append(elems, data.MakePair(data.Nil[upperIdent](), data.EMakePair[constrainer](spec, pattern(v))))
+		}
+	}
+	if len(elems) == 0 {
+		return data.Nothing[constraintVerified](g.at)
+	}
+	return data.Just(constraintVerified{data.Construct(elems[0], elems[1:]...)})
+}
+
+// returns the lowercase names of `pat` in the order they appear
+func nameVariables(pat pattern) []name {
+	switch p := pat.(type) {
+	case name:
+		if isLowerName(p) {
+			return []name{p}
+		}
+	case patternApp:
+		vars := nameVariables(p.Fst())
+		for _, arg := range p.Snd().Elements() {
+			vars = append(vars, nameVariables(arg)...)
+		}
+		return vars
+	case patternEnclosed:
+		var vars []name
+		for _, elem := range p.Elements() {
+			vars = append(vars, nameVariables(elem)...)
+		}
+		return vars
+	}
+	return nil
+}
+
+// compares each pair of fields with `member` from left to right, stopping at the first result that
+// isn't `equal`, e.g., for `(==)` and two fields,
+//
+//	case (==) x'1 y'1 of (
+//		True => (==) x'2 y'2
+//		False => False
+//	)
+func (g generator) lexicographic(member string, xs, ys []name, equal string, unequal ...string) expr {
+	if len(xs) == 0 {
+		return g.name(equal)
+	}
+
+	last := len(xs) - 1
+	e := g.app(g.name(member), xs[last], ys[last])
+	for i := last - 1; i >= 0; i-- {
+		arms := []caseArm{g.arm(g.name(equal), e)}
+		for _, result := range unequal {
+			arms = append(arms, g.arm(g.name(result), g.name(result)))
+		}
+		e = g.caseOf(g.patternApp(g.name(member), xs[i], ys[i]), arms...)
+	}
+	return e
+}
+
+// structural equality: values are equal iff they're built by the same constructor from equal fields
+func deriveEq(g generator, cons []derivedConstructor) []def {
+	defs := make([]def, 0, len(cons)+1)
+	for _, con := range cons {
+		xs, ys := g.variables("x", len(con.fields)), g.variables("y", len(con.fields))
+		lhs := g.patternApp(g.name("=="), g.constructor(con, xs), g.constructor(con, ys))
+		defs = append(defs, g.def(lhs, g.lexicographic("==", xs, ys, "True", "False")))
+	}
+	if len(cons) > 1 {
+		defs = append(defs, g.def(g.patternApp(g.name("=="), g.wildcard(), g.wildcard()), g.name("False")))
+	}
+	return defs
+}
+
+// structural ordering: values built by different constructors are ordered by the order the
+// constructors are defined in, and values built by the same constructor are ordered by their fields
+func deriveOrd(g generator, cons []derivedConstructor) []def {
+	defs := make([]def, 0, 3*len(cons))
+	for i, con := range cons {
+		xs, ys := g.variables("x", len(con.fields)), g.variables("y", len(con.fields))
+		lhs := g.patternApp(g.name("compare"), g.constructor(con, xs), g.constructor(con, ys))
+		defs = append(defs, g.def(lhs, g.lexicographic("compare", xs, ys, "EQ", "LT", "GT")))
+		if i == len(cons)-1 {
+			break // both values must be built by the last constructor
+		}
+
+		// every constructor before `con` has been ruled out for both values
+		defs = append(defs,
+			g.def(g.patternApp(g.name("compare"), g.anyOf(con), g.wildcard()), g.name("LT")),
+			g.def(g.patternApp(g.name("compare"), g.wildcard(), g.anyOf(con)), g.name("GT")),
+		)
+	}
+	return defs
+}
+
+// printing: values are shown as their constructor applied to each of its fields, e.g.,
+// `(Cons x xs)`
+func deriveShow(g generator, cons []derivedConstructor) []def {
+	defs := make([]def, 0, len(cons))
+	for _, con := range cons {
+		xs := g.variables("x", len(con.fields))
+		lhs := g.patternApp(g.name("show"), g.constructor(con, xs))
+		if len(xs) == 0 {
+			defs = append(defs, g.def(lhs, g.string(nameString(con.name))))
+			continue
+		}
+
+		// "(C " ++ show x'1 ++ " " ++ .. ++ show x'n ++ ")"
+		e := g.app(g.name("++"), g.app(g.name("show"), xs[len(xs)-1]), g.string(")"))
+		for i := len(xs) - 2; i >= 0; i-- {
+			e = g.app(g.name("++"), g.app(g.name("show"), xs[i]), g.app(g.name("++"), g.string(" "), e))
+		}
+		e = g.app(g.name("++"), g.string("("+nameString(con.name)+" "), e)
+		defs = append(defs, g.def(lhs, e))
+	}
+	return defs
+}
@@ -0,0 +1,63 @@
+//go:build test
+// +build test
+
+package parser
+
+import "testing"
+
+const derivingPrelude = `Ordering : Type where (
+  LT : Ordering
+  EQ : Ordering
+  GT : Ordering
+)
+
+String : Type
+
+(++) : String -> String -> String
+
+spec Eq a where (
+  (==) : a -> a -> Bool
+)
+
+spec Eq a => Ord a where (
+  compare : a -> a -> Ordering
+)
+
+spec Show a where (
+  show : a -> String
+)
+
+Color : Type where (
+  Red : Color
+  Green : Color
+  Blue : Color
+) deriving (Eq Color, Ord Color, Show Color)
+
+`
+
+func TestDeriveInstances(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []report
+	}{
+		{"prelude", "", nil},
+		{"eq", "t : Bool\nt = (==) Red Blue", nil},
+		{"ord", "t : Ordering\nt = compare Red Blue", nil},
+		{"show", "t : String\nt = show Red", nil},
+		{"fields", "Box : Type -> Type where (\n  MkBox : a -> Color -> Box a\n) deriving (Eq a, Show (Box a))\n\nt : Bool\nt = (==) (MkBox Red Red) (MkBox Blue Green)", nil},
+		{"missing context instance", "Box : Type -> Type where (\n  MkBox : a -> Box a\n) deriving Eq a\n\nt : Bool\nt = (==) (MkBox Zero) (MkBox Zero)", []report{typeError(MissingInstance + ": `Eq Nat`")}},
+		{"recursive", "Tree : Type -> Type where (\n  Leaf : Tree a\n  Node : Tree a -> a -> Tree a -> Tree a\n) deriving (Eq a, Ord a)\n\nt : Ordering\nt = compare (Node Leaf Red Leaf) Leaf", nil},
+		{"not derived", "t : Bool\nt = (==) Zero Zero", []report{typeError(MissingInstance + ": `Eq Nat`")}},
+		{"unsupported spec", "Unit : Type where (\n  MkUnit : Unit\n) deriving Read Unit", []report{typeError(UnsupportedDeriving + ": `Read`")}},
+		{"unsupported constructor", "Fn : Type where (\n  MkFn : Nat -> (Nat -> Nat) -> Fn\n) deriving Eq Fn", []report{typeError(UnsupportedDerivingConstructor + ": `MkFn`")}},
+		{"unsupported type", "Void : Type where impossible deriving Eq Void", []report{typeError(UnsupportedDerivingType + ": `Void`")}},
+		{"illegal target", "Unit : Type where (\n  MkUnit : Unit\n) deriving Eq Nat", []report{typeError(IllegalDerivingTarget + ": `Unit`")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectReports(t, checkSource(t, typeCheckPrelude+derivingPrelude+test.source), test.expected...)
+		})
+	}
+}
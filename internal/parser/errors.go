@@ -9,7 +9,13 @@ import (
 )
 
 const (
+	AmbiguousSyntax                 = "ambiguous use of syntax; parenthesize to disambiguate"                        // ambiguous-syntax
+	BadAnnotationArguments          = "annotation has bad arguments"                                                 // bad-annotation-arguments
 	BadImport                       = "expected package name or import group"                                        // bad-import
+	CapturedSyntaxName              = "name in syntax expansion is bound where the syntax is used"                   // captured-syntax-name
+	ConflictingFixity               = "operator is declared with conflicting fixities"                               // conflicting-fixity
+	DuplicateBinding                = "name is bound more than once"                                                 // duplicate-binding
+	DuplicateDefinition             = "name is already defined"                                                      // duplicate-definition
 	ExpectedAccessDot               = "expected '.'"                                                                 // expected-access-dot
 	ExpectedAliasBinding            = "expected '=' to follow type alias name"                                       // expected-alias-binding
 	ExpectedAuto                    = "expected 'auto'"                                                              // expected-auto
@@ -24,6 +30,7 @@ const (
 	ExpectedConstraint              = "expected type constraint"                                                     // expected-type-constraint
 	ExpectedConstraintElem          = "expected constraint element"                                                  // expected-constraint-elem
 	ExpectedDef                     = "expected definition"                                                          // expected-def
+	ExpectedDefBody                 = "expected definition body"                                                     // expected-def-body
 	ExpectedDerivingBody            = "expected body for deriving clause"                                            // expected-deriving-body
 	ExpectedEndOfFile               = "expected end of file"                                                         // expected-eof
 	ExpectedExpr                    = "expected expression"                                                          // expected-expr
@@ -55,6 +62,7 @@ const (
 	ExpectedStringLit               = "expected literal"                                                             // expected-lit
 	ExpectedSymbol                  = "expected symbol"                                                              // expected-symbol
 	ExpectedSyntax                  = "expected syntax definition"                                                   // expected-syntax
+	ExpectedSyntaxBinder            = "syntax binder must be matched by a variable"                                  // expected-syntax-binder
 	ExpectedSyntaxBinding           = "expected '=' to follow syntax rule"                                           // expected-syntax-binding
 	ExpectedSyntaxBindingId         = "expected syntax binding identifier"                                           // expected-syntax-binding-id
 	ExpectedSyntaxRule              = "expected syntax rule"                                                         // expected-syntax-rule
@@ -71,25 +79,55 @@ const (
 	ExpectedWithArmThickArrow       = "expected '=>' to follow with arm pattern"                                     // expected-with-arm-thick-arrow
 	ExpectedWithClause              = "expected 'with' clause"                                                       // expected-with-clause
 	ExpectedWithClauseArm           = "expected 'with' clause arm"                                                   // expected-with-clause-arm
+	IllegalAliasTarget              = "type aliases can only alias type constructors"                                // illegal-alias-target
+	IllegalApplication              = "term cannot be applied to an argument"                                        // illegal-application
+	IllegalConstructorResult        = "constructor must construct a member of its type"                              // illegal-constructor-result
+	IllegalDerivingTarget           = "derived instances must be for the type being defined"                         // illegal-deriving-target
 	IllegalEmptyUsingClause         = "illegal empty using clause"                                                   // illegal-empty-using-clause
+	IllegalErasedUse                = "erased variable cannot be used at runtime"                                    // illegal-erased-use
+	IllegalLinearUse                = "'once' variable must be used exactly once"                                    // illegal-linear-use
 	IllegalLowercaseConstructorName = "constructor names cannot be lowercase identifiers"                            // illegal-lowercase-constructor-name
 	IllegalMethodTypeConstructor    = "type constructors cannot be identified by method identifiers"                 // illegal-method-type-constructor
 	IllegalMultipleEnclosure        = "illegal multiply enclosed term"                                               // illegal-multiple-enclosure
 	IllegalNamespaceAlias           = "illegal namespace alias, expected lowercase identifier"                       // illegal-namespace-alias
 	IllegalOpenModifier             = "modifier 'open' can only target data type definitions"                        // illegal-open-modifier
 	IllegalOpenModifierTyping       = "modifier 'open' targeted a typing, but no constructors were found"            // illegal-open-modifier-typing
+	IllegalRefinement               = "pattern does not refine the patterns of its clause"                           // illegal-refinement
 	IllegalUnenclosedUsingClause    = "illegal unenclosed symbol selection in using clause"                          // illegal-unenclosed-using-clause
 	IllegalVisibilityTarget         = "illegal target for visibility modifier"                                       // illegal-visibility-target
 	IllegalVisibleDef               = "visibility modifiers cannot be applied to definitions, only their signatures" // illegal-visible-def
+	InfiniteType                    = "type would be infinite"                                                       // infinite-type
 	InvalidAnnotationTarget         = "cannot find a valid target for annotations"                                   // invalid-annotation-target
+	MisplacedAnnotation             = "annotation cannot target this node"                                           // misplaced-annotation
+	MissingOperand                  = "operator is missing an operand"                                               // missing-operand
+	MissingInstance                 = "no instance satisfies constraint"                                             // missing-instance
+	MissingMember                   = "instance does not implement a required member"                                // missing-member
+	MixedAssociativity              = "operators of equal precedence associate in different directions"              // mixed-associativity
+	NonAssociativeChain             = "non-associative operators of equal precedence must be parenthesized"          // non-associative-chain
+	OverlappingInstances            = "instances overlap"                                                            // overlapping-instances
+	OverlappingSyntax               = "syntax rule starts with the same keyword as another rule"                     // overlapping-syntax
+	SyntaxMismatch                  = "expression does not match syntax rule"                                        // syntax-mismatch
+	TooManyParameters               = "definition has more parameters than its type allows"                          // too-many-parameters
+	TypeMismatch                    = "type mismatch"                                                                // type-mismatch
+	UnboundName                     = "name is not bound"                                                            // unbound-name
+	UndeclaredMember                = "instance implements a member its spec does not declare"                       // undeclared-member
 	UnexpectedEOF                   = "unexpected end of file"                                                       // unexpected-eof
 	UnexpectedStructure             = "unexpected structure in source body"                                          // unexpected-structure
 	UnexpectedToken                 = "unexpected token"                                                             // unexpected-token
+	UnsolvedImplicit                = "cannot infer implicit argument"                                               // unsolved-implicit
+	UnsupportedDeriving             = "spec cannot be derived"                                                       // unsupported-deriving
+	UnsupportedDerivingConstructor  = "cannot derive an instance for constructor"                                    // unsupported-deriving-constructor
+	UnsupportedDerivingType         = "cannot derive an instance for a type without constructors"                    // unsupported-deriving-type
 )
 
 func parseError(p parser, e data.Err) error {
-	start, end := e.Pos()
-	return errors.Syntax(p.srcCode(), e.Msg(), start, end)
+	src, start, end := p.locate(e.Pos())
+	return errors.Syntax(src, e.Msg(), start, end)
+}
+
+func parseWarning(p parser, e data.Err) error {
+	src, start, end := p.locate(e.Pos())
+	return errors.Warning(src, e.Msg(), start, end)
 }
 
 // given a token, report some error relating to a type constructor name
@@ -0,0 +1,436 @@
+// =================================================================================================
+// fixity resolution: re-associates infix operator applications by the fixities declared with
+// `infixl`, `infixr`, and `infix` annotations
+// =================================================================================================
+
+package parser
+
+import (
+	"strconv"
+
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/api/annotate"
+	"github.com/petersalex27/yew/api/token"
+	"github.com/petersalex27/yew/common/data"
+	"github.com/petersalex27/yew/internal/errors"
+)
+
+type associativity byte
+
+const (
+	leftAssociative associativity = iota
+	rightAssociative
+	nonAssociative
+)
+
+// annotations declaring fixities, e.g., `[@infixl 6 (+) (-)]` or `--@infixr 0 ($)`
+var fixityAnnotations = map[string]associativity{
+	"infixl": leftAssociative,
+	"infixr": rightAssociative,
+	"infix":  nonAssociative,
+}
+
+type fixity struct {
+	associativity
+	// operators of higher precedence bind tighter
+	precedence int
+	// where the fixity is declared, nil for the default fixity
+	at api.Positioned
+}
+
+// fixity of operators without a declared fixity
+var defaultFixity = fixity{associativity: leftAssociative, precedence: 9}
+
+// the "operator" an application spine starts with; it binds looser than any other operator
+var spineStart = fixity{associativity: nonAssociative, precedence: -1}
+
+type fixityResolver struct {
+	reporter
+	// fixities of operators, keyed by operator
+	fixities map[string]fixity
+}
+
+// re-associates the infix operator applications in the expressions, patterns, and types of `ps`
+//
+// fixities are declared by the annotations of the meta section, imports (declaring the fixities of
+// imported operators), top-level body elements and their members, and the footer; fixities are
+// global, regardless of where they're declared. Application binds tighter than any operator
+func resolveFixity(ps *ParserState) {
+	r := &fixityResolver{reporter: reporter{ps, errors.Syntax}, fixities: make(map[string]fixity)}
+	if h, just := ps.ast.header.Break(); just {
+		if m, just := h.Fst().Break(); just {
+			r.declare(m.annotations)
+		}
+		for _, statement := range h.Snd().Elements() {
+			r.declare(statement.Fst())
+		}
+	}
+
+	b, just := ps.ast.body.Break()
+	if just {
+		for _, elem := range bodyElements(b) {
+			r.declareElement(elem)
+		}
+	}
+	r.declare(ps.ast.footer.Maybe)
+	if !just {
+		return
+	}
+
+	elems := data.Nil[bodyElement](b.Len())
+	for _, elem := range bodyElements(b) {
+		elems = elems.Snoc(r.mainElement(elem).asBodyElement())
+	}
+	ps.ast.body = data.Just(body{elems})
+}
+
+// declares the fixities annotating `elem` and its members
+func (r *fixityResolver) declareElement(elem mainElement) {
+	switch e := elem.(type) {
+	case def:
+		r.declare(e.annotations)
+	case typing:
+		r.declare(e.annotations)
+	case typeDef:
+		r.declare(e.annotations)
+		if constructors, _, isImpossible := e.typedef.Snd().Break(); !isImpossible {
+			for _, constructor := range constructors.Elements() {
+				r.declare(constructor.annotations)
+			}
+		}
+	case typeAlias:
+		r.declare(e.annotations)
+	case specDef:
+		r.declare(e.annotations)
+		r.declareMembers(e.specBody)
+	case specInst:
+		r.declare(e.annotations)
+		r.declareMembers(e.body)
+	case syntax:
+		r.declare(e.annotations)
+	}
+}
+
+func (r *fixityResolver) declareMembers(body specBody) {
+	for _, member := range body.Elements() {
+		r.declareElement(data.Cases(member, (def).asMainElement, (typing).asMainElement))
+	}
+}
+
+// declares the fixities of `as`, ignoring annotations that don't declare fixities
+//
+// annotations are validated before fixities are resolved, so each fixity annotation is known to
+// take a precedence followed by one or more operators
+func (r *fixityResolver) declare(as data.Maybe[annotations]) {
+	annots, just := as.Break()
+	if !just {
+		return
+	}
+
+	for _, annot := range annots.Elements() {
+		name, args, _ := annotationArgs(annot)
+		if assoc, isFixity := fixityAnnotations[name]; isFixity {
+			r.declareFixity(assoc, args, annot)
+		}
+	}
+}
+
+// declares the fixity of each operator in `args`, which are a precedence followed by operators,
+// e.g., `6 (+) (-)`
+func (r *fixityResolver) declareFixity(assoc associativity, args []annotate.Arg, at api.Positioned) {
+	precedence, _ := strconv.Atoi(args[0].Value)
+	f := fixity{associativity: assoc, precedence: precedence, at: at}
+	for _, arg := range args[1:] {
+		op := arg.Value
+		if other, declared := r.fixities[op]; declared && (other.associativity != assoc || other.precedence != precedence) {
+			r.errorWithNote(ConflictingFixity+": ("+op+")", at, "fixity is also declared here", other.at)
+			continue
+		}
+		r.fixities[op] = f
+	}
+}
+
+func (r *fixityResolver) fixityOf(operator name) fixity {
+	if f, found := r.fixities[nameString(operator)]; found {
+		return f
+	}
+	return defaultFixity
+}
+
+// resolves the chain of operators `operators` whose operands are `operands`, where `operators[i]`
+// is applied infix between `operands[i]` and `operands[i+1]`
+//
+// operators of equal precedence associate in the direction they're declared to; it's an error for
+// them to associate in different directions or to not associate at all
+func resolveChain[a any](r *fixityResolver, operands []a, operators []name, apply func(operator name, lhs, rhs a) a) a {
+	i := 0 // index of the next operator
+	var resolve func(prev fixity, prevOperator name, lhs a) a
+	resolve = func(prev fixity, prevOperator name, lhs a) a {
+		for i < len(operators) {
+			operator := operators[i]
+			next := r.fixityOf(operator)
+			if prev.precedence == next.precedence {
+				if prev.associativity != next.associativity {
+					r.errorAt(MixedAssociativity+": `"+nameString(prevOperator)+"` and `"+nameString(operator)+"`", operator)
+				} else if next.associativity == nonAssociative {
+					r.errorAt(NonAssociativeChain+": `"+nameString(prevOperator)+"` and `"+nameString(operator)+"`", operator)
+				}
+			}
+			if prev.precedence > next.precedence || (prev.precedence == next.precedence && prev.associativity != rightAssociative) {
+				return lhs
+			}
+
+			i++
+			rhs := resolve(next, operator, operands[i])
+			lhs = apply(operator, lhs, rhs)
+		}
+		return lhs
+	}
+	return resolve(spineStart, name{}, operands[0])
+}
+
+// splits an application spine at its operators, returning nil operands if an operator is missing
+// an operand; an operand is the application of each of its expressions
+func splitSpine[a api.Node](r *fixityResolver, spine []a, app func([]a) a) (operands []a, operators []name) {
+	start := 0
+	for i := 0; i <= len(spine); i++ {
+		if i < len(spine) {
+			n, isOperator := asOperator(spine[i])
+			if !isOperator {
+				continue
+			}
+			operators = append(operators, n)
+		}
+
+		if i == start {
+			at := operators[len(operators)-1]
+			r.errorAt(MissingOperand+": `"+nameString(at)+"`", at)
+			return nil, operators
+		}
+		operands = append(operands, app(spine[start:i]))
+		start = i + 1
+	}
+	return operands, operators
+}
+
+// returns `x` as a name if it's an operator applied infix, e.g., `+` in `x + y`--but not in `(+) x y`
+func asOperator(x api.Node) (n name, ok bool) {
+	n, isName := x.(name)
+	return n, isName && isInfixName(n) && token.Id.Match(n.Extract())
+}
+
+// returns the prefix form of an operator applied infix, e.g., `(+)` for `+`
+func prefixOperator(operator name) name {
+	if tok, isToken := operator.Extract().(token.Token); isToken {
+		tok.Typ = token.Infix
+		return data.EOne[name](api.Token(tok))
+	}
+	return operator
+}
+
+func (r *fixityResolver) mainElement(elem mainElement) mainElement {
+	switch e := elem.(type) {
+	case def:
+		return r.def(e)
+	case typing:
+		return r.typing(e)
+	case typeDef:
+		e.typedef = data.MakePair(r.typing(e.typedef.Fst()), e.typedef.Snd())
+		if constructors, _, isImpossible := e.typedef.Snd().Break(); !isImpossible {
+			constructors = data.MapNonEmpty(func(constructor typeConstructor) typeConstructor {
+				constructor.constructor = data.MakePair(constructor.constructor.Fst(), r.typ(constructor.constructor.Snd()))
+				return constructor
+			})(constructors)
+			e.typedef = data.MakePair(e.typedef.Fst(), data.Inl[impossible](constructors))
+		}
+		return e
+	case typeAlias:
+		e.alias = data.MakePair(e.alias.Fst(), r.typ(e.alias.Snd()))
+		return e
+	case specDef:
+		e.specBody = specBody{data.MapNonEmpty(r.specMember)(e.specBody.NonEmpty)}
+		if requiring, just := e.requiring.Break(); just {
+			e.requiring = data.Just(data.MapNonEmpty(r.def)(requiring))
+		}
+		return e
+	case specInst:
+		e.body = specBody{data.MapNonEmpty(r.specMember)(e.body.NonEmpty)}
+		return e
+	case syntax:
+		e.rule = data.MakePair(e.rule.Fst(), r.expr(e.rule.Snd()))
+		return e
+	}
+	return elem
+}
+
+func (r *fixityResolver) specMember(member specMember) specMember {
+	return data.EitherMap(r.def, r.typing)(member)
+}
+
+func (r *fixityResolver) typing(ty typing) typing {
+	ty.typing = data.MakePair(ty.typing.Fst(), r.typ(ty.typing.Snd()))
+	return ty
+}
+
+func (r *fixityResolver) def(d def) def {
+	d.pattern = r.pattern(d.pattern)
+	d.defBody = r.defBody(d.defBody)
+	return d
+}
+
+func (r *fixityResolver) defBody(body defBody) defBody {
+	_, possible, isPossible := body.Break()
+	if !isPossible {
+		return body
+	}
+
+	var rhs data.Either[withClause, expr]
+	if with, e, isExpr := possible.Fst().Break(); isExpr {
+		rhs = data.Inr[withClause](r.expr(e))
+	} else {
+		rhs = data.Inl[expr](r.withClause(with))
+	}
+	where := data.MaybeMap(func(where whereClause) whereClause {
+		return whereClause{data.MapNonEmpty(r.mainElement)(where.NonEmpty)}
+	})(possible.Snd())
+	resolved := data.EMakePair[defBodyPossible](rhs, where)
+	resolved.Position = possible.Position
+	return data.EInr[defBody](resolved)
+}
+
+func (r *fixityResolver) withClause(w withClause) withClause {
+	arms := data.MapNonEmpty(func(arm withClauseArm) withClauseArm {
+		lhs := data.EitherMap(r.pattern, data.PairMap(r.pattern, r.pattern))(arm.Fst())
+		resolved := data.EMakePair[withClauseArm](lhs, r.defBody(arm.Snd()))
+		resolved.Position = arm.Position
+		return resolved
+	})(w.Snd().NonEmpty)
+	resolved := data.EMakePair[withClause](r.pattern(w.Fst()), withClauseArms{arms})
+	resolved.Position = w.Position
+	return resolved
+}
+
+func (r *fixityResolver) expr(e expr) expr {
+	switch x := e.(type) {
+	case exprApp:
+		spine := append([]expr{x.Fst()}, x.Snd().Elements()...)
+		for i, elem := range spine {
+			spine[i] = r.expr(elem)
+		}
+		operands, operators := splitSpine(r, spine, func(elems []expr) expr {
+			if len(elems) == 1 {
+				return elems[0]
+			}
+			return data.EMakePair[exprApp](elems[0], data.Construct(elems[1], elems[2:]...))
+		})
+		if len(operators) == 0 || operands == nil {
+			return data.EMakePair[exprApp](spine[0], data.Construct(spine[1], spine[2:]...)).updatePosExpr(x)
+		}
+		return resolveChain(r, operands, operators, func(operator name, lhs, rhs expr) expr {
+			return data.EMakePair[exprApp](expr(prefixOperator(operator)), data.Construct(lhs, rhs))
+		}).updatePosExpr(x)
+	case lambdaAbstraction:
+		binders := data.MapNonEmpty(func(b lambdaBinder) lambdaBinder {
+			return lambdaBinder{data.EitherMap(r.binder, func(w wildcard) wildcard { return w })(b.Either)}
+		})(x.Fst().NonEmpty)
+		resolved := data.EMakePair[lambdaAbstraction](lambdaBinders{binders}, r.expr(x.Snd()))
+		resolved.Position = x.Position
+		return resolved
+	case letExpr:
+		group := data.MapNonEmpty(func(member bindingGroupMember) bindingGroupMember {
+			return data.EitherMap(
+				data.PairMap(r.binder, r.expr),
+				data.PairMap(r.typing, data.MaybeMap(r.expr)),
+			)(member)
+		})(x.Fst().NonEmpty)
+		resolved := data.EMakePair[letExpr](letBinding{group}, r.expr(x.Snd()))
+		resolved.Position = x.Position
+		return resolved
+	case caseExpr:
+		arms := data.MapNonEmpty(func(arm caseArm) caseArm {
+			resolved := data.EMakePair[caseArm](r.pattern(arm.Fst()), r.defBody(arm.Snd()))
+			resolved.Position = arm.Position
+			return resolved
+		})(x.Snd().NonEmpty)
+		resolved := data.EMakePair[caseExpr](r.pattern(x.Fst()), caseArms{arms})
+		resolved.Position = x.Position
+		return resolved
+	}
+	return e
+}
+
+func (r *fixityResolver) binder(b binder) binder {
+	return data.EitherMap(func(id ident) ident { return id }, r.pattern)(b)
+}
+
+func (r *fixityResolver) pattern(pat pattern) pattern {
+	switch p := pat.(type) {
+	case patternApp:
+		spine := append([]pattern{p.Fst()}, p.Snd().Elements()...)
+		for i, elem := range spine {
+			spine[i] = r.pattern(elem)
+		}
+		operands, operators := splitSpine(r, spine, func(elems []pattern) pattern {
+			if len(elems) == 1 {
+				return elems[0]
+			}
+			return data.EMakePair[patternApp](elems[0], data.Construct(elems[1], elems[2:]...))
+		})
+		if len(operators) == 0 || operands == nil {
+			return data.EMakePair[patternApp](spine[0], data.Construct(spine[1], spine[2:]...)).updatePosPattern(p)
+		}
+		return resolveChain(r, operands, operators, func(operator name, lhs, rhs pattern) pattern {
+			return data.EMakePair[patternApp](pattern(prefixOperator(operator)), data.Construct(lhs, rhs))
+		}).updatePosPattern(p)
+	case patternEnclosed:
+		p.NonEmpty = data.MapNonEmpty(r.pattern)(p.NonEmpty)
+		return p
+	}
+	return pat
+}
+
+func (r *fixityResolver) typ(ty typ) typ {
+	switch t := ty.(type) {
+	case appType:
+		spine := append([]typ{t.Fst()}, t.Snd().Elements()...)
+		for i, elem := range spine {
+			spine[i] = r.typ(elem)
+		}
+		operands, operators := splitSpine(r, spine, func(elems []typ) typ {
+			if len(elems) == 1 {
+				return elems[0]
+			}
+			return data.EMakePair[appType](elems[0], data.Construct(elems[1], elems[2:]...))
+		})
+		if len(operators) == 0 || operands == nil {
+			return data.EMakePair[appType](spine[0], data.Construct(spine[1], spine[2:]...)).updatePosTyp(t)
+		}
+		return resolveChain(r, operands, operators, func(operator name, lhs, rhs typ) typ {
+			return data.EMakePair[appType](typ(prefixOperator(operator)), data.Construct(lhs, rhs))
+		}).updatePosTyp(t)
+	case functionType:
+		resolved := data.EMakePair[functionType](r.typ(t.Fst()), r.typ(t.Snd()))
+		resolved.Position = t.Position
+		return resolved
+	case enclosedType:
+		t.typ = r.typ(t.typ)
+		return t
+	case forallType:
+		resolved := data.EMakePair[forallType](t.Fst(), r.typ(t.Snd()))
+		resolved.Position = t.Position
+		return resolved
+	case constrainedType:
+		resolved := data.EMakePair[constrainedType](t.Fst(), r.typ(t.Snd()))
+		resolved.Position = t.Position
+		return resolved
+	case innerTyping:
+		t.typing = data.MakePair(t.typing.Fst(), r.typ(t.typing.Snd()))
+		return t
+	case implicitTyping:
+		inner, _ := r.typ(t.Fst()).(innerTyping)
+		resolved := data.EMakePair[implicitTyping](inner, t.Snd())
+		resolved.Position = t.Position
+		return resolved
+	}
+	return ty
+}
@@ -0,0 +1,104 @@
+//go:build test
+// +build test
+
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const fixityPrelude = `[@infixl 6 (+) (-)]
+(+) : Nat -> Nat -> Nat
+(-) : Nat -> Nat -> Nat
+
+--@infixl 7 (*)
+(*) : Nat -> Nat -> Nat
+
+--@infixr 6 (<+)
+(<+) : Nat -> Nat -> Nat
+
+--@infix 4 (==)
+(==) : Nat -> Nat -> Bool
+
+`
+
+// renders `e` with each operator applied prefix and each nested application parenthesized, e.g.,
+// `(+) a ((*) b c)` for `a + b * c`
+func prefixString(e expr) string {
+	switch x := e.(type) {
+	case exprApp:
+		elems := append([]expr{x.Fst()}, x.Snd().Elements()...)
+		strs := make([]string, len(elems))
+		for i, elem := range elems {
+			strs[i] = prefixString(elem)
+			if _, isApp := elem.(exprApp); isApp {
+				strs[i] = "(" + strs[i] + ")"
+			}
+		}
+		return strings.Join(strs, " ")
+	case name:
+		if isInfixName(x) {
+			return "(" + nameString(x) + ")"
+		}
+		return nameString(x)
+	}
+	return "?"
+}
+
+// returns the right-hand side of the last clause of `f` in the body of `ps`
+func bodyOfF(t *testing.T, ps *ParserState) expr {
+	t.Helper()
+	b, _ := ps.ast.body.Break()
+	elems := bodyElements(b)
+	for i := len(elems) - 1; i >= 0; i-- {
+		d, isDef := elems[i].(def)
+		if !isDef || definedNameString(d) != "f" {
+			continue
+		}
+		if _, possible, isPossible := d.defBody.Break(); isPossible {
+			if _, e, isExpr := possible.Fst().Break(); isExpr {
+				return e
+			}
+		}
+	}
+	t.Fatalf("expected a definition of `f`")
+	return nil
+}
+
+func TestResolveFixity(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		tree     string // right-hand side of `f` after resolution, or empty if `f` isn't checked
+		expected []report
+	}{
+		{"precedence", "f : Nat -> Bool\nf n = n + n == n - n", "(==) ((+) n n) ((-) n n)", nil},
+		{"higher precedence", "f : Nat -> Nat -> Nat -> Nat\nf a b c = a + b * c", "(+) a ((*) b c)", nil},
+		{"higher precedence first", "f : Nat -> Nat -> Nat -> Nat\nf a b c = a * b + c", "(+) ((*) a b) c", nil},
+		{"left associative", "f : Nat -> Nat -> Nat -> Nat -> Nat\nf a b c d = a + b - c + d", "(+) ((-) ((+) a b) c) d", nil},
+		{"right associative", "f : Nat -> Nat -> Nat -> Nat\nf a b c = a <+ b <+ c", "(<+) a ((<+) b c)", nil},
+		{"application binds tighter", "f : Nat -> Nat\nf n = Succ n + Succ (Succ n)", "(+) (Succ n) (Succ (Succ n))", nil},
+		{"pattern", "--@infixr 5 (:>)\nStream : Type where (\n  (:>) : Nat -> Stream -> Stream\n  End : Stream\n)\n\nsecond : Stream -> Nat\nsecond (_ :> x :> _) = x\nsecond _ = Zero", "", nil},
+		{"type", "--@infixr 0 (~>)\n(~>) : Type -> Type -> Type\n\nf : Nat ~> Nat ~> Nat", "", nil},
+		{"mixed associativity", "f : Nat -> Nat\nf n = n + n <+ n", "", []report{syntaxError(MixedAssociativity + ": `+` and `<+`")}},
+		{"non-associative chain", "f : Nat -> Bool\nf n = n == n == n", "", []report{syntaxError(NonAssociativeChain + ": `==` and `==`")}},
+		{"parenthesized", "f : Nat -> Nat -> Nat -> Nat\nf a b c = a + (b <+ c)", "(+) a ((<+) b c)", nil},
+		{"missing operand", "f : Nat -> Nat\nf n = n +", "", []report{syntaxError(MissingOperand + ": `+`")}},
+		{"conflicting", "--@infixr 6 (+)\ng : Nat", "", []report{syntaxError(ConflictingFixity + ": (+)")}},
+		{"bad fixity", "--@infixl (+)\ng : Nat", "", []report{syntaxError(BadAnnotationArguments)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ps := checkSource(t, typeCheckPrelude+fixityPrelude+test.source)
+			expectReports(t, ps, test.expected...)
+			if test.tree == "" {
+				return
+			}
+			if actual := prefixString(bodyOfF(t, ps)); actual != test.tree {
+				t.Errorf("expected `%s`, got `%s`", test.tree, actual)
+			}
+		})
+	}
+}
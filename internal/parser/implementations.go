@@ -413,3 +413,14 @@ func (w wildcard) updatePosPattern(p api.Positioned) pattern {
 	w.Position = w.Update(p)
 	return w
 }
+
+// = yewSource =====================================================================================
+
+// yewSource implements api.SourceRoot
+func (n yewSource) Header() api.Node { return n.header }
+
+// yewSource implements api.SourceRoot
+func (n yewSource) Body() api.Node { return n.body }
+
+// yewSource implements api.SourceRoot
+func (n yewSource) Footer() api.Node { return n.footer }
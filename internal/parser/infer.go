@@ -0,0 +1,91 @@
+// =================================================================================================
+// inference: checks a successfully analyzed AST like `Check`, then infers the type of an untyped
+// definition or the kind of a typing, e.g., for the expressions and types the REPL is asked about
+// =================================================================================================
+
+package parser
+
+import (
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/internal/symbol"
+)
+
+// Infer the type of the untyped, parameterless definition `x` after checking the types of a
+// successfully analyzed parser's AST, returning the inferred type and true iff no errors were
+// reported
+//
+// SEE: `Check`
+func Infer(p parser, x string) (ty string, ok bool) {
+	ps, isState := p.(*ParserState)
+	if !isState {
+		return "", false
+	}
+	n := len(ps.errors)
+	c := checkTypes(ps)
+	if _, found := definitionBody(ps, x); !found || len(ps.errors) != n {
+		return "", false
+	}
+	inferred, _ := c.types.Lookup(x)
+	return symbol.String(withoutTypeBinders(c.unifier.Apply(inferred))), true
+}
+
+// returns `ty` without its leading implicit binders of types, e.g., `{a : _} -> a -> a` becomes
+// `a -> a`
+func withoutTypeBinders(ty api.Type) api.Type {
+	for {
+		pi, isPi := ty.(symbol.Pi)
+		if !isPi || !pi.Implicit() || pi.Constraint() {
+			return ty
+		}
+		ty = pi.Target()
+	}
+}
+
+// InferKind infers the kind of the type `x` is declared to have after checking the types of a
+// successfully analyzed parser's AST, returning the inferred kind and true iff no errors were
+// reported
+//
+// SEE: `Check`
+func InferKind(p parser, x string) (kind string, ok bool) {
+	ps, isState := p.(*ParserState)
+	if !isState {
+		return "", false
+	}
+	n := len(ps.errors)
+	c := checkTypes(ps)
+	ty, found := c.typings[x]
+	if !found || len(ps.errors) != n {
+		return "", false
+	}
+	return symbol.String(c.unifier.Apply(c.typeOf(ty))), len(ps.errors) == n
+}
+
+// returns the body of the top-level definition of `x` if it has neither parameters nor a with or
+// where clause
+func definitionBody(ps *ParserState, x string) (body expr, found bool) {
+	b, just := ps.ast.body.Break()
+	if !just {
+		return nil, false
+	}
+
+	for _, elem := range bodyElements(b) {
+		d, isDef := elem.(def)
+		if !isDef {
+			continue
+		}
+		n, params, ok := definedName(d.pattern)
+		if !ok || nameString(n) != x || len(params) != 0 {
+			continue
+		}
+		_, possible, isPossible := d.defBody.Break()
+		if !isPossible {
+			return nil, false
+		}
+		if _, hasWhere := possible.Snd().Break(); hasWhere {
+			return nil, false
+		}
+		_, e, isExpr := possible.Fst().Break()
+		return e, isExpr
+	}
+	return nil, false
+}
@@ -0,0 +1,416 @@
+// =================================================================================================
+// instance resolution: registers the instances of each spec and resolves constraints
+// =================================================================================================
+
+package parser
+
+import (
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/internal/symbol"
+)
+
+// limit on how deeply the constraints of instances are resolved, e.g., resolving `Eq (List Nat)`
+// with `inst Eq a => Eq (List a)` resolves `Eq Nat` one level deeper
+const maxResolutionDepth = 32
+
+// a spec defined in the source
+type specInfo struct {
+	// parameters of the spec, e.g., `a` in `spec Eq a`--parameters that aren't variables are empty
+	params []string
+	// indices of the parameters that determine the others (see `from`), nil if every parameter does
+	determining []int
+	// constraints implied by the spec, e.g., `Eq a` for `spec Eq a => Ord a`
+	context []api.Type
+	// names of the members every instance must implement
+	required []name
+	// type of each member as it's declared in the spec, e.g., `a -> a -> Bool` for `eq` in
+	// `spec Eq a where (eq : a -> a -> Bool)`
+	members map[string]api.Type
+}
+
+// binds each parameter of the spec in `ty` with an implicit, erased binder
+//
+// parameters are bound even when they share a name with a symbol in scope
+func (info specInfo) bind(ty api.Type, types *symbol.Table) api.Type {
+	for i := len(info.params) - 1; i >= 0; i-- {
+		if info.params[i] != "" {
+			ty = symbol.MakePi(info.params[i], symbol.Erase, true, types.Fresh(), ty)
+		}
+	}
+	return ty
+}
+
+// returns true iff the `i`-th parameter of the spec determines which instance is chosen
+func (info specInfo) determines(i int) bool {
+	if info.determining == nil {
+		return true
+	}
+	for _, j := range info.determining {
+		if i == j {
+			return true
+		}
+	}
+	return false
+}
+
+// an instance of a spec
+type instanceInfo struct {
+	// head of the instance, e.g., `Eq (List a)` in `inst Eq a => Eq (List a)`
+	head constrainer
+	// arguments of the spec being instantiated, e.g., `List a` in `inst Eq a => Eq (List a)`
+	args []api.Type
+	// constraints the instance depends on, e.g., `Eq a` in `inst Eq a => Eq (List a)`
+	context []api.Type
+}
+
+// a constraint required by an application
+type wantedConstraint struct {
+	constraint api.Type
+	// application requiring the constraint
+	at api.Positioned
+	// source of the constrained type, nil if unknown
+	from api.Positioned
+}
+
+func makeConstraint(spec string, args []api.Type) api.Type {
+	return append(api.App{symbol.MakeConstant("", spec)}, args...)
+}
+
+// elaborates a pattern in a type-level position, e.g., `List a` in `Eq (List a)`
+func (c *typeChecker) patternType(pat pattern) api.Type {
+	switch p := pat.(type) {
+	case name:
+		return c.elaborateName(p)
+	case literal:
+		return symbol.MakeConstant("", p.Extract().String())
+	case patternApp:
+		out := api.App{c.patternType(p.Fst())}
+		for _, arg := range p.Snd().Elements() {
+			out = append(out, c.patternType(arg))
+		}
+		return out
+	case patternEnclosed:
+		if p.Len() == 1 {
+			return c.patternType(p.Head())
+		}
+	}
+	return c.unknown()
+}
+
+// returns the arguments of a constrainer, e.g., `a` and `b` in `Cast a b`
+func (c *typeChecker) constrainerArgs(pat pattern) []api.Type {
+	app, isApp := pat.(patternApp)
+	if !isApp {
+		return []api.Type{c.patternType(pat)}
+	}
+
+	args := []api.Type{c.patternType(app.Fst())}
+	for _, arg := range app.Snd().Elements() {
+		args = append(args, c.patternType(arg))
+	}
+	return args
+}
+
+func (c *typeChecker) constrainerType(cr constrainer) api.Type {
+	return makeConstraint(nameString(name(cr.Fst())), c.constrainerArgs(cr.Snd()))
+}
+
+// elaborates each constraint of `con`, e.g., `Eq a` and `Ord a` for `(Eq a, Ord a)`
+func (c *typeChecker) elaborateConstraint(con constraint) []api.Type {
+	switch x := con.(type) {
+	case constraintUnverified:
+		enclosed, isEnclosed := x.Extract().(enclosedType)
+		if !isEnclosed {
+			return []api.Type{c.elaborate(x.Extract())}
+		} else if terms, isTerms := enclosed.typ.(innerTypeTerms); isTerms {
+			out := make([]api.Type, 0, terms.Len())
+			for _, term := range terms.Elements() {
+				out = append(out, c.elaborate(term))
+			}
+			return out
+		}
+		return []api.Type{c.elaborate(enclosed.typ)}
+	case constraintVerified:
+		return c.verifiedConstraints(x)
+	}
+	return nil
+}
+
+// elaborates each constraint of `con`, e.g., `Eq a` and `Ord a` for `(Eq, Ord a)`
+func (c *typeChecker) verifiedConstraints(con constraintVerified) []api.Type {
+	var out []api.Type
+	for _, elem := range con.Elements() {
+		constraint := c.constrainerType(elem.Snd())
+		_, args := constraint.Break()
+		for _, id := range elem.Fst().Elements() {
+			out = append(out, makeConstraint(nameString(name(id)), args))
+		}
+		out = append(out, constraint)
+	}
+	return out
+}
+
+// registers a spec and declares each of its members, constrained by the spec, e.g.,
+// `eq : Eq a => a -> a -> Bool` for `spec Eq a where (eq : a -> a -> Bool)`
+func (c *typeChecker) declareSpec(spec specDef) {
+	head := spec.specHead.Snd()
+	x := nameString(name(head.Fst()))
+	c.types.DeclareTyped(x, c.unknown())
+
+	var info specInfo
+	args := c.constrainerArgs(head.Snd())
+	for _, arg := range args {
+		param, _ := symbol.IsVariable(arg)
+		info.params = append(info.params, param)
+	}
+	if dep, just := spec.dependency.Break(); just {
+		info.determining = []int{}
+		for _, arg := range c.constrainerArgs(dep) {
+			param, _ := symbol.IsVariable(arg)
+			for i := range info.params {
+				if param != "" && info.params[i] == param {
+					info.determining = append(info.determining, i)
+				}
+			}
+		}
+	}
+	if con, just := spec.specHead.Fst().Break(); just {
+		info.context = c.verifiedConstraints(con)
+	}
+
+	// members with a definition in the spec body have a default implementation
+	defaults := make(map[string]bool)
+	for _, member := range spec.specBody.Elements() {
+		if d, _, isTyping := member.Break(); !isTyping {
+			if n, _, ok := definedName(d.pattern); ok {
+				defaults[nameString(n)] = true
+			}
+		}
+	}
+
+	constraint := makeConstraint(x, args)
+	required := make(map[string]bool)
+	info.members = make(map[string]api.Type)
+	for _, member := range spec.specBody.Elements() {
+		_, ty, isTyping := member.Break()
+		if !isTyping {
+			continue
+		}
+		n := ty.typing.Fst()
+		c.typings[nameString(n)] = ty.typing.Snd()
+		info.members[nameString(n)] = c.elaborate(ty.typing.Snd())
+		c.types.DeclareTyped(nameString(n), c.generalize(info.bind(symbol.MakeConstraint(constraint, info.members[nameString(n)]), c.types)))
+		if !defaults[nameString(n)] {
+			info.required = append(info.required, n)
+			required[nameString(n)] = true
+		}
+	}
+
+	// members of the requiring clause must be implemented by every instance, even if they have a
+	// default implementation
+	if requiring, just := spec.requiring.Break(); just {
+		for _, d := range requiring.Elements() {
+			if n, _, ok := definedName(d.pattern); ok && !required[nameString(n)] {
+				info.required = append(info.required, n)
+				required[nameString(n)] = true
+			}
+		}
+	}
+
+	c.specs[x] = info
+}
+
+// returns the constrainer naming the spec an instance instantiates, e.g., `Cast a c` in
+// `inst (Cast a b, Cast b c) => TransitiveCast = Cast a c`
+func instanceHead(inst specInst) constrainer {
+	if target, just := inst.target.Break(); just {
+		return target
+	}
+	return inst.head.Snd()
+}
+
+// registers an instance, reporting it if it overlaps a previously registered instance
+func (c *typeChecker) declareInstance(inst specInst) {
+	if !inst.target.IsNothing() {
+		c.types.DeclareTyped(nameString(name(inst.head.Snd().Fst())), c.unknown())
+	}
+
+	head := instanceHead(inst)
+	spec := nameString(name(head.Fst()))
+	info := instanceInfo{head: head, args: c.constrainerArgs(head.Snd())}
+	if con, just := inst.head.Fst().Break(); just {
+		info.context = c.verifiedConstraints(con)
+	}
+
+	for _, other := range c.instances[spec] {
+		if c.overlaps(c.specs[spec], other, info) {
+			c.errorWithNote(OverlappingInstances+": `"+symbol.String(makeConstraint(spec, info.args))+"`", head, "overlaps this instance", other.head)
+			break
+		}
+	}
+	c.instances[spec] = append(c.instances[spec], info)
+}
+
+// returns copies of the arguments and context of `inst` with each type variable replaced by a fresh
+// free variable
+func (c *typeChecker) freshenInstance(inst instanceInfo) (args, context []api.Type) {
+	all := append(append(api.App{}, inst.args...), inst.context...)
+	for _, x := range symbol.FreeVariables(all) {
+		all = symbol.Substitute(all, x, c.types.Fresh()).(api.App)
+	}
+	return all[:len(inst.args)], all[len(inst.args):]
+}
+
+// returns true iff some constraint is satisfied by both `a` and `b`
+func (c *typeChecker) overlaps(spec specInfo, a, b instanceInfo) bool {
+	argsA, _ := c.freshenInstance(a)
+	argsB, _ := c.freshenInstance(b)
+	return matchArgs(symbol.NewUnifier(), spec, argsA, argsB)
+}
+
+// returns true iff each determining argument of `as` unifies with its counterpart in `bs`
+func matchArgs(u *symbol.Unifier, spec specInfo, as, bs []api.Type) bool {
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if spec.determines(i) && u.Unify(as[i], bs[i]) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// checks each member `inst` implements against its type in the spec, reporting each member the spec
+// doesn't declare, then reports each member the spec requires that `inst` does not implement
+func (c *typeChecker) instanceMembers(inst specInst) {
+	head := instanceHead(inst)
+	info, defined := c.specs[nameString(name(head.Fst()))]
+	if !defined {
+		return // spec is defined elsewhere, so its members are unknown
+	}
+
+	var context []api.Type
+	if con, just := inst.head.Fst().Break(); just {
+		context = c.verifiedConstraints(con)
+	}
+	args := c.constrainerArgs(head.Snd())
+
+	implemented := make(map[string]bool)
+	for _, member := range inst.body.Elements() {
+		if d, _, isTyping := member.Break(); !isTyping {
+			if n, _, ok := definedName(d.pattern); ok {
+				implemented[nameString(n)] = true
+				if ty, isMember := info.members[nameString(n)]; isMember {
+					c.defAs(d, c.instantiateMember(info, args, context, ty))
+				} else {
+					c.errorAt(UndeclaredMember+": `"+nameString(n)+"`", n)
+				}
+			}
+		}
+	}
+
+	for _, member := range info.required {
+		if !implemented[nameString(member)] {
+			c.errorWithNote(MissingMember+": `"+nameString(member)+"`", head, "member is declared here", member)
+		}
+	}
+}
+
+// returns the type a member of type `ty` has in an instance of `spec` with the arguments `args` and
+// the context `context`, e.g., `Eq a => List a -> List a -> Bool` for `eq : a -> a -> Bool` in
+// `inst Eq a => Eq (List a)`
+func (c *typeChecker) instantiateMember(spec specInfo, args, context []api.Type, ty api.Type) api.Type {
+	for i, param := range spec.params {
+		if param != "" && i < len(args) {
+			ty = symbol.Substitute(ty, param, args[i])
+		}
+	}
+	for i := len(context) - 1; i >= 0; i-- {
+		ty = symbol.MakeConstraint(context[i], ty)
+	}
+	return c.generalize(ty)
+}
+
+// assumes `constraint` holds, along with each constraint its spec implies
+func (c *typeChecker) addGiven(constraint api.Type) {
+	c.givens = append(c.givens, constraint)
+
+	spec, args := constraint.Break()
+	info, defined := c.specs[spec]
+	if !defined || len(args) != len(info.params) {
+		return
+	}
+	for _, implied := range info.context {
+		for i, param := range info.params {
+			if param != "" {
+				implied = symbol.Substitute(implied, param, args[i])
+			}
+		}
+		c.addGiven(implied)
+	}
+}
+
+// returns true iff `constraint` is assumed to hold
+func (c *typeChecker) given(constraint api.Type) bool {
+	for _, g := range c.givens {
+		if symbol.NewUnifier().Unify(g, constraint) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// resolves each constraint wanted since the `mark`-th one
+func (c *typeChecker) solveConstraints(mark int) {
+	pending := c.wanted[mark:]
+	c.wanted = c.wanted[:mark]
+
+	for _, w := range pending {
+		c.resolve(w, w.constraint, 0)
+	}
+}
+
+// finds an instance satisfying `constraint`, reporting `w` if there is none
+//
+// constraints on specs defined elsewhere and constraints with undetermined arguments are never
+// reported
+func (c *typeChecker) resolve(w wantedConstraint, constraint api.Type, depth int) {
+	constraint = c.unifier.Apply(constraint)
+	spec, args := constraint.Break()
+	info, defined := c.specs[spec]
+	if !defined || depth > maxResolutionDepth || c.given(constraint) {
+		return
+	}
+	for i, arg := range args {
+		if info.determines(i) && symbol.HasFree(arg) {
+			return
+		}
+	}
+
+	for _, inst := range c.instances[spec] {
+		instArgs, context := c.freshenInstance(inst)
+		u := symbol.NewUnifier()
+		if !matchArgs(u, info, instArgs, args) {
+			continue
+		}
+
+		// arguments determined by the others are improved by the chosen instance
+		for i := range args {
+			if !info.determines(i) {
+				c.unifier.Unify(u.Apply(instArgs[i]), args[i])
+			}
+		}
+		for _, con := range context {
+			c.resolve(w, u.Apply(con), depth+1)
+		}
+		return
+	}
+
+	msg := MissingInstance + ": `" + symbol.String(constraint) + "`"
+	if w.from == nil {
+		c.errorAt(msg, w.at)
+	} else {
+		c.errorWithNote(msg, w.at, "constraint is required here", w.from)
+	}
+}
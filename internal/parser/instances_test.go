@@ -0,0 +1,61 @@
+//go:build test
+// +build test
+
+package parser
+
+import "testing"
+
+const instancesPrelude = `spec Eq a where (
+  eq : a -> a -> Bool
+)
+
+spec Eq a => Ord a where (
+  lt : a -> a -> Bool
+)
+
+inst Eq Nat where (
+  eq x y = True
+)
+
+inst Eq a => Eq (List a) where (
+  eq xs ys = True
+)
+
+same : Eq a => a -> a -> Bool
+same x y = eq x y
+
+`
+
+func TestResolveInstances(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []report
+	}{
+		{"prelude", "", nil},
+		{"instance", "t : Bool\nt = same Zero Zero", nil},
+		{"instance context", "t : Bool\nt = same (Cons Zero Nil) Nil", nil},
+		{"missing instance", "t : Bool\nt = same True False", []report{typeError(MissingInstance + ": `Eq Bool`")}},
+		{"missing context instance", "t : Bool\nt = same (Cons True Nil) Nil", []report{typeError(MissingInstance + ": `Eq Bool`")}},
+		{"given", "f : Eq a => a -> Bool\nf x = eq x x", nil},
+		{"missing given", "f : a -> Bool\nf x = eq x x", []report{typeError(MissingInstance + ": `Eq a`")}},
+		{"implied given", "f : Ord a => a -> Bool\nf x = eq x x", nil},
+		{"overlapping instances", "inst Eq (List Nat) where (\n  eq xs ys = False\n)", []report{typeError(OverlappingInstances + ": `Eq (List Nat)`")}},
+		{"missing member", "inst Eq Bool where (\n  lt x y = True\n)", []report{typeError(UndeclaredMember + ": `lt`"), typeError(MissingMember + ": `eq`")}},
+		{"undeclared member", "inst Eq Bool where (\n  eq x y = True\n  lt x y = False\n)", []report{typeError(UndeclaredMember + ": `lt`")}},
+		{"member", "inst Eq Bool where (\n  eq x y = x\n)", nil},
+		{"member mismatch", "spec Equal a where (\n  (==) : a -> a -> Bool\n)\n\ninst Equal Bool where (\n  (==) = 3\n)", []report{typeError(TypeMismatch + ": expected `Bool -> Bool -> Bool`, got `Int`")}},
+		{"instantiated member mismatch", "inst Eq Bool where (\n  eq x y = Zero\n)", []report{typeError(TypeMismatch + ": expected `Bool`, got `Nat`")}},
+		{"member context", "Maybe : Type -> Type where (\n  Nothing : Maybe a\n  Just : a -> Maybe a\n)\n\nf : Eq a => a -> Bool\n\ninst Eq a => Eq (Maybe a) where (\n  eq (Just x) _ = f x\n  eq _ _ = False\n)", nil},
+		{"member missing context", "Maybe : Type -> Type where (\n  Nothing : Maybe a\n  Just : a -> Maybe a\n)\n\nf : Eq a => a -> Bool\n\ninst Eq (Maybe a) where (\n  eq (Just x) _ = f x\n  eq _ _ = False\n)", []report{typeError(MissingInstance + ": `Eq a`")}},
+		{"default member", "spec Show a where (\n  show : a -> Nat\n  shows : a -> Nat\n  shows x = show x\n)\n\ninst Show Nat where (\n  show x = x\n)", nil},
+		{"requiring", "spec Show a where (\n  show : a -> Nat\n  shows : a -> Nat\n  shows x = show x\n) requiring (\n  shows x = show x\n)\n\ninst Show Nat where (\n  show x = x\n)", []report{typeError(MissingMember + ": `shows`")}},
+		{"dependency", "spec Has f e from f where (\n  get : f -> e\n)\n\ninst Has (List Nat) Nat where (\n  get xs = Zero\n)\n\ninst Has (List Nat) Bool where (\n  get xs = True\n)", []report{typeError(OverlappingInstances + ": `Has (List Nat) Bool`")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectReports(t, checkSource(t, typeCheckPrelude+instancesPrelude+test.source), test.expected...)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// =================================================================================================
+// introspection: lists the instances of a spec and the public surface of a module, e.g., for the
+// REPL's `:instances` and `:api`
+// =================================================================================================
+
+package parser
+
+import (
+	"strings"
+
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/api/util"
+	"github.com/petersalex27/yew/common/data"
+	"github.com/petersalex27/yew/internal/symbol"
+)
+
+// an instance of a spec
+type Instance struct {
+	// head of the instance, e.g., `Eq (List a)` in `inst Eq a => Eq (List a)`
+	Head string
+	// constraints the instance depends on, e.g., `Eq a` in `inst Eq a => Eq (List a)`
+	Context []string
+	// path of the source the instance is declared in, e.g., a file added by `Include`
+	Path string
+	// location of the instance's head (or, for a derived instance, of the deriving clause)
+	Line, Char int
+}
+
+// returns the instance as it would be declared, e.g., `inst Eq a => Eq (List a)`
+func (inst Instance) String() string {
+	switch len(inst.Context) {
+	case 0:
+		return "inst " + inst.Head
+	case 1:
+		return "inst " + inst.Context[0] + " => " + inst.Head
+	}
+	return "inst (" + strings.Join(inst.Context, ", ") + ") => " + inst.Head
+}
+
+// a declaration of a module's public surface
+type Declaration struct {
+	// signature of the declaration as it's written, e.g., `add : Nat -> Nat -> Nat` or
+	// `spec Eq a where`
+	Signature string
+	// signatures of the constructors of a type definition or the members of a spec
+	Members []string
+}
+
+// Instances lists every instance of `spec` in the order they're declared after checking the types
+// of a successfully analyzed parser's AST, returning the instances and true iff no errors were
+// reported
+//
+// SEE: `Check`
+func Instances(p parser, spec string) (instances []Instance, ok bool) {
+	ps, isState := p.(*ParserState)
+	if !isState {
+		return nil, false
+	}
+	n := len(ps.errors)
+	c := checkTypes(ps)
+	if len(ps.errors) != n {
+		return nil, false
+	}
+
+	for _, info := range c.instances[spec] {
+		src, start, _ := ps.locate(info.head.Pos())
+		line, char := util.CalcLocation(src, start, false)
+		inst := Instance{Head: symbol.String(makeConstraint(spec, info.args)), Path: src.Path(), Line: line, Char: char}
+		for _, con := range info.context {
+			inst.Context = append(inst.Context, symbol.String(con))
+		}
+		instances = append(instances, inst)
+	}
+	return instances, true
+}
+
+// Surface lists the typings, type definitions, type aliases, specs, and syntax rules of a
+// successfully parsed parser's AST that are marked `public` or `open`, in the order they're
+// declared
+//
+// source without a module header isn't a module, so everything it declares is listed
+func Surface(p parser) (declarations []Declaration) {
+	ps, isState := p.(*ParserState)
+	if !isState {
+		return nil
+	}
+	b, just := ps.ast.body.Break()
+	if !just {
+		return nil
+	}
+
+	everything := !hasModule(ps)
+	for _, elem := range bodyElements(b) {
+		if decl, vis, isDecl := declaration(ps, elem); isDecl && (everything || !vis.IsNothing()) {
+			declarations = append(declarations, decl)
+		}
+	}
+	return declarations
+}
+
+// returns true iff the source of `ps` begins with a module header
+func hasModule(ps *ParserState) bool {
+	h, just := ps.ast.header.Break()
+	return just && !h.Fst().IsNothing()
+}
+
+// returns the declaration `elem` makes and its visibility, or false if `elem` isn't part of a
+// module's surface, e.g., a definition or an instance
+func declaration(ps *ParserState, elem mainElement) (decl Declaration, vis data.Maybe[visibility], isDecl bool) {
+	switch e := elem.(type) {
+	case typing:
+		return Declaration{Signature: sourceSpan(ps, e.typing.Fst(), e.typing.Snd())}, e.visibility, true
+	case typeDef:
+		ty := e.typedef.Fst().typing
+		decl.Signature = sourceSpan(ps, ty.Fst(), ty.Snd()) + " where"
+		if constructors, _, isImpossible := e.typedef.Snd().Break(); !isImpossible {
+			for _, constructor := range constructors.Elements() {
+				decl.Members = append(decl.Members, sourceSpan(ps, constructor.constructor.Fst(), constructor.constructor.Snd()))
+			}
+		} else {
+			decl.Signature += " impossible"
+		}
+		return decl, e.visibility, true
+	case typeAlias:
+		return Declaration{Signature: "alias " + sourceSpan(ps, e.alias.Fst(), e.alias.Snd())}, e.visibility, true
+	case specDef:
+		var end api.Positioned = e.specHead.Snd()
+		if dependency, just := e.dependency.Break(); just {
+			end = dependency
+		}
+		decl.Signature = "spec " + sourceSpan(ps, e.specHead, end) + " where"
+		for _, member := range e.specBody.Elements() {
+			if _, ty, isTyping := member.Break(); isTyping {
+				decl.Members = append(decl.Members, sourceSpan(ps, ty.typing.Fst(), ty.typing.Snd()))
+			}
+		}
+		return decl, e.visibility, true
+	case syntax:
+		return Declaration{Signature: "syntax " + sourceSpan(ps, e.rule.Fst(), e.rule.Fst())}, e.visibility, true
+	}
+	return decl, vis, false
+}
+
+// returns the source from the start of `from` to the end of `to`, collapsing each run of whitespace
+// into a single space
+func sourceSpan(ps *ParserState, from, to api.Positioned) string {
+	start, _ := from.Pos()
+	_, end := to.Pos()
+	src, start, end := ps.locate(start, end)
+	return strings.Join(strings.Fields(src.String()[start:end]), " ")
+}
@@ -0,0 +1,126 @@
+//go:build test
+// +build test
+
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/petersalex27/yew/api/util"
+	"github.com/petersalex27/yew/internal/lexer"
+)
+
+func TestInstances(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected []string
+	}{
+		{"instances", "Eq", []string{"inst Eq Nat", "inst Eq a => Eq (List a)"}},
+		{"no instances", "Ord", nil},
+		{"not a spec", "Show", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := Init(lexer.Init(util.StringSource(typeCheckPrelude + instancesPrelude)))
+			if Run(p) == nil || !Analyze(p) {
+				t.Fatalf("unexpected failure: %v", p.Errors())
+			}
+
+			instances, ok := Instances(p, test.spec)
+			if !ok {
+				t.Fatalf("unexpected failure: %v", p.Errors())
+			}
+			var actual []string
+			for _, inst := range instances {
+				if inst.Line == 0 {
+					t.Errorf("expected a location for `%v`", inst)
+				}
+				actual = append(actual, inst.String())
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestInstanceLocation(t *testing.T) {
+	const source = `-- λx. x, ∀a. a
+Nat : Type where Zero : Nat
+spec Eq a where (
+  eq : a -> a -> Nat
+)
+inst Eq Nat where (
+  eq x y = Zero
+)`
+
+	p := Init(lexer.Init(util.FreeSource("eq.yew", source)))
+	if Run(p) == nil || !Analyze(p) {
+		t.Fatalf("unexpected failure: %v", p.Errors())
+	}
+	instances, ok := Instances(p, "Eq")
+	if !ok || len(instances) != 1 {
+		t.Fatalf("expected one instance, got %v (errors: %v)", instances, p.Errors())
+	}
+	if inst := instances[0]; inst.Path != "eq.yew" || inst.Line != 6 || inst.Char != 6 {
+		t.Errorf("expected `%v` at eq.yew:6:6, got %s:%d:%d", inst, inst.Path, inst.Line, inst.Char)
+	}
+}
+
+func TestSurface(t *testing.T) {
+	const source = `Nat : Type where (
+  Zero : Nat
+  Succ : Nat -> Nat
+)
+
+spec Eq a where (
+  eq : a -> a -> Nat
+)
+
+inst Eq Nat where (
+  eq x y = Zero
+)
+
+alias N = Nat
+
+syntax ` + "`twice`" + ` x = Succ (Succ x)
+
+add : Nat -> Nat -> Nat
+add x y = x`
+
+	all := []Declaration{
+		{Signature: "Nat : Type where", Members: []string{"Zero : Nat", "Succ : Nat -> Nat"}},
+		{Signature: "spec Eq a where", Members: []string{"eq : a -> a -> Nat"}},
+		{Signature: "alias N = Nat"},
+		{Signature: "syntax `twice` x"},
+		{Signature: "add : Nat -> Nat -> Nat"},
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected []Declaration
+	}{
+		{"script", source, all},
+		{"module", "module m\n\npublic " + source, all[:1]},
+		{"open", "module m\n\nopen " + source, all[:1]},
+		{"dependency", "spec Has f e from f where (\n  get : f -> e\n)", []Declaration{{Signature: "spec Has f e from f where", Members: []string{"get : f -> e"}}}},
+		{"private module", "module m\n\n" + source, nil},
+		{"non-ASCII", "-- λx. x, ∀a. a\n" + source, all},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := Init(lexer.Init(util.StringSource(test.source)))
+			if Run(p) == nil {
+				t.Fatalf("unexpected failure: %v", p.Errors())
+			}
+			if actual := Surface(p); !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+// =================================================================================================
+// lowering: translates the AST of a checked yew source into the untyped core language
+// =================================================================================================
+
+package parser
+
+import (
+	"github.com/petersalex27/yew/api/token"
+	"github.com/petersalex27/yew/internal/core"
+)
+
+// lowers the constructors, definitions, and spec members of the body of `ps`
+func lowerProgram(ps *ParserState) core.Program {
+	program := core.NewProgram()
+	b, just := ps.ast.body.Break()
+	if !just {
+		return program
+	}
+
+	elems := bodyElements(b)
+	for _, f := range lowerDefs(elems) {
+		program.Funcs[f.Name] = f
+	}
+
+	for _, elem := range elems {
+		switch e := elem.(type) {
+		case typeDef:
+			lowerConstructors(program, e)
+		case specDef:
+			lowerSpec(program, e)
+		case specInst:
+			lowerInstance(program, e)
+		}
+	}
+	program.CompileMatches()
+	return program
+}
+
+func lowerConstructors(program core.Program, td typeDef) {
+	constructors, _, isImpossible := td.typedef.Snd().Break()
+	if isImpossible {
+		return
+	}
+
+	ty := nameString(td.typedef.Fst().typing.Fst())
+	for _, constructor := range constructors.Elements() {
+		n, conTy := constructor.constructor.Split()
+		program.Constructors[nameString(n)] = core.Constructor{Name: nameString(n), Type: ty, Arity: len(constructorFields(conTy))}
+	}
+}
+
+// returns the method implementing the spec member `x`, adding it to `program` if it's missing
+func method(program core.Program, x string) *core.Method {
+	m, found := program.Methods[x]
+	if !found {
+		m = &core.Method{Name: x}
+		program.Methods[x] = m
+	}
+	return m
+}
+
+func lowerSpec(program core.Program, spec specDef) {
+	var defs []mainElement
+	for _, member := range spec.specBody.Elements() {
+		d, ty, isTyping := member.Break()
+		if isTyping {
+			method(program, nameString(ty.typing.Fst()))
+		} else {
+			defs = append(defs, d)
+		}
+	}
+
+	for _, f := range lowerDefs(defs) {
+		method(program, f.Name).Default = &f
+	}
+}
+
+func lowerInstance(program core.Program, inst specInst) {
+	var defs []mainElement
+	for _, member := range inst.body.Elements() {
+		if d, _, isTyping := member.Break(); !isTyping {
+			defs = append(defs, d)
+		}
+	}
+
+	ty := instanceType(inst)
+	for _, f := range lowerDefs(defs) {
+		m := method(program, f.Name)
+		m.Instances = append(m.Instances, core.Instance{Type: ty, Func: f})
+	}
+}
+
+// returns the name of the type an instance is for, e.g., `List` for `inst Eq a => Eq (List a)`, or
+// "" if the instance is for any type
+func instanceType(inst specInst) string {
+	pat := instanceHead(inst).Snd()
+	if app, isApp := pat.(patternApp); isApp {
+		pat = app.Fst() // the first argument of the spec decides the instance
+	}
+	return patternHead(pat)
+}
+
+// returns the constructor at the head of a type-level pattern, e.g., `List` for `(List a)`, or "" if
+// the head isn't a constructor
+func patternHead(pat pattern) string {
+	switch p := pat.(type) {
+	case name:
+		if !isLowerName(p) {
+			return nameString(p)
+		}
+	case patternApp:
+		return patternHead(p.Fst())
+	case patternEnclosed:
+		if p.Len() == 1 && !p.implicit {
+			return patternHead(p.Head())
+		}
+	}
+	return ""
+}
+
+// groups the clauses of the definitions in `elems` into functions, in the order each function is
+// first defined
+func lowerDefs(elems []mainElement) []core.Func {
+	index := make(map[string]int)
+	var funcs []core.Func
+	for _, elem := range elems {
+		d, isDef := elem.(def)
+		if !isDef {
+			continue
+		}
+		n, params, ok := definedName(d.pattern)
+		if !ok {
+			continue
+		}
+
+		x := nameString(n)
+		i, found := index[x]
+		if !found {
+			i = len(funcs)
+			index[x] = i
+			funcs = append(funcs, core.Func{Name: x})
+		}
+		if clause, possible := lowerClause(defLhs(n, params, nil), d.defBody); possible {
+			funcs[i].Clauses = append(funcs[i].Clauses, clause)
+		}
+	}
+	return funcs
+}
+
+// lowers a clause, returning false if the clause is `impossible`
+func lowerClause(lhs refinable, body defBody) (clause core.Clause, possible bool) {
+	_, p, possible := body.Break()
+	if !possible {
+		return clause, false
+	}
+
+	for _, param := range lhs.params {
+		if enclosed, isEnclosed := param.(patternEnclosed); isEnclosed && enclosed.implicit {
+			continue // implicit arguments aren't passed at runtime
+		}
+		clause.Patterns = append(clause.Patterns, lowerPattern(param))
+	}
+	clause.Body = lowerBody(p, lhs)
+	return clause, true
+}
+
+// lowers a definition body, where `lhs` is the left-hand side the body belongs to
+func lowerBody(body defBodyPossible, lhs refinable) core.Term {
+	var out core.Term
+	if w, e, isExpr := body.Fst().Break(); isExpr {
+		out = lowerExpr(e)
+	} else {
+		out = lowerWith(w, lhs)
+	}
+
+	if where, just := body.Snd().Break(); just {
+		out = core.Let{Funcs: lowerDefs(where.Elements()), Body: out}
+	}
+	return out
+}
+
+func lowerExpr(e expr) core.Term {
+	switch x := e.(type) {
+	case name:
+		return core.Var{Name: nameString(x)}
+	case literal:
+		return lowerLiteral(x)
+	case hole:
+		return core.Fail{Reason: "cannot evaluate hole `" + nameString(x) + "`"}
+	case exprApp:
+		return lowerSpine(append([]expr{x.Fst()}, x.Snd().Elements()...))
+	case lambdaAbstraction:
+		params := make([]core.Pattern, 0, x.Fst().Len())
+		for _, binder := range x.Fst().Elements() {
+			b, _, isWildcard := binder.Either.Break()
+			if isWildcard {
+				params = append(params, core.PWild{})
+			} else {
+				params = append(params, lowerBinding(b))
+			}
+		}
+		return core.Lam{Params: params, Body: lowerExpr(x.Snd())}
+	case letExpr:
+		return lowerLet(x.Fst(), lowerExpr(x.Snd()))
+	case caseExpr:
+		var arms []core.Clause
+		for _, arm := range x.Snd().Elements() {
+			if clause, possible := lowerClause(armLhs(arm.Fst(), nil), arm.Snd()); possible {
+				arms = append(arms, clause)
+			}
+		}
+		return core.Case{Scrutinee: lowerPatternTerm(x.Fst()), Arms: arms}
+	}
+	return core.Fail{Reason: "cannot evaluate " + e.Type().String()}
+}
+
+// lowers an application spine; its operators are already applied prefix (see `resolveFixity`)
+func lowerSpine(spine []expr) core.Term {
+	args := make([]core.Term, len(spine)-1)
+	for i, arg := range spine[1:] {
+		args[i] = lowerExpr(arg)
+	}
+	return core.App{Fun: lowerExpr(spine[0]), Args: args}
+}
+
+// lowers a let binding group; variables are bound recursively, and each pattern is matched against
+// its bound value before `body`
+func lowerLet(group letBinding, body core.Term) core.Term {
+	var funcs []core.Func
+	var matches []core.Case
+	for _, member := range group.Elements() {
+		bound, ty, isTyping := member.Break()
+		if isTyping {
+			if e, just := ty.Snd().Break(); just {
+				funcs = append(funcs, core.Func{Name: nameString(ty.Fst().typing.Fst()), Clauses: []core.Clause{{Body: lowerExpr(e)}}})
+			}
+			continue
+		}
+
+		pat := lowerBinding(bound.Fst())
+		if v, isVar := pat.(core.PVar); isVar {
+			funcs = append(funcs, core.Func{Name: v.Name, Clauses: []core.Clause{{Body: lowerExpr(bound.Snd())}}})
+		} else {
+			matches = append(matches, core.Case{Scrutinee: lowerExpr(bound.Snd()), Arms: []core.Clause{{Patterns: []core.Pattern{pat}}}})
+		}
+	}
+
+	for i := len(matches) - 1; i >= 0; i-- {
+		matches[i].Arms[0].Body = body
+		body = matches[i]
+	}
+	return core.Let{Funcs: funcs, Body: body}
+}
+
+func lowerBinding(b binder) core.Pattern {
+	id, pat, isPattern := b.Break()
+	if isPattern {
+		return lowerPattern(pat)
+	}
+	return lowerPattern(identAsName(id))
+}
+
+func lowerLiteral(lit literal) core.Lit {
+	tok := lit.Extract()
+	switch {
+	case token.IntValue.Match(tok):
+		return core.Lit{Kind: core.IntLit, Value: tok.String()}
+	case token.FloatValue.Match(tok):
+		return core.Lit{Kind: core.FloatLit, Value: tok.String()}
+	case token.CharValue.Match(tok):
+		return core.Lit{Kind: core.CharLit, Value: tok.String()}
+	}
+	return core.Lit{Kind: core.StringLit, Value: tok.String()}
+}
+
+func lowerPattern(pat pattern) core.Pattern {
+	switch p := pat.(type) {
+	case name:
+		if isLowerName(p) {
+			return core.PVar{Name: nameString(p)}
+		}
+		return core.PCon{Name: nameString(p)}
+	case literal:
+		return core.PLit{Lit: lowerLiteral(p)}
+	case patternApp:
+		return lowerPatternSpine(append([]pattern{p.Fst()}, p.Snd().Elements()...))
+	case patternEnclosed:
+		if p.Len() == 1 {
+			return lowerPattern(p.Head())
+		}
+		args := make([]core.Pattern, p.Len())
+		for i, elem := range p.Elements() {
+			args[i] = lowerPattern(elem)
+		}
+		return core.PCon{Name: ",", Args: args}
+	case access:
+		return core.PAccess{Name: nameString(p)}
+	}
+	// wildcards and holes match anything
+	return core.PWild{}
+}
+
+// lowers a constructor pattern's spine
+func lowerPatternSpine(spine []pattern) core.Pattern {
+	var args []core.Pattern
+	for _, arg := range spine[1:] {
+		if enclosed, isEnclosed := arg.(patternEnclosed); isEnclosed && enclosed.implicit {
+			continue // implicit arguments aren't passed at runtime
+		}
+		args = append(args, lowerPattern(arg))
+	}
+	return core.PCon{Name: patternHead(spine[0]), Args: args}
+}
+
+// lowers a pattern in an expression position, e.g., the scrutinee of a case expression
+func lowerPatternTerm(pat pattern) core.Term {
+	switch p := pat.(type) {
+	case name:
+		return core.Var{Name: nameString(p)}
+	case literal:
+		return lowerLiteral(p)
+	case patternApp:
+		return lowerPatternTermSpine(append([]pattern{p.Fst()}, p.Snd().Elements()...))
+	case patternEnclosed:
+		if p.Len() == 1 && !p.implicit {
+			return lowerPatternTerm(p.Head())
+		}
+	}
+	return core.Fail{Reason: "cannot evaluate " + pat.Type().String()}
+}
+
+func lowerPatternTermSpine(spine []pattern) core.Term {
+	args := make([]core.Term, len(spine)-1)
+	for i, arg := range spine[1:] {
+		args[i] = lowerPatternTerm(arg)
+	}
+	return core.App{Fun: lowerPatternTerm(spine[0]), Args: args}
+}
@@ -0,0 +1,661 @@
+// =================================================================================================
+// name analysis: binds and resolves every identifier of a successfully parsed yew source
+// =================================================================================================
+
+package parser
+
+import (
+	"strings"
+
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/common/data"
+	"github.com/petersalex27/yew/internal/common"
+	"github.com/petersalex27/yew/internal/errors"
+	"github.com/petersalex27/yew/internal/symbol"
+)
+
+// names that are always in scope
+var builtinNames = []string{"Type"}
+
+type nameAnalyzer struct {
+	reporter
+	names *symbol.Table
+	// true iff some import brings all of a package's symbols into scope (`using _`); when this is the
+	// case, names that cannot be resolved are assumed to come from that package
+	openImport bool
+	// when true, binding a name that shadows another does not produce a warning
+	quiet bool
+	// names of all declared type constructors (data types and type aliases)
+	typeConstructors map[string]bool
+}
+
+// analyzes the names in the AST of `ps`, reporting all unbound names, duplicate definitions, and
+// shadowed names
+func analyzeNames(ps *ParserState) {
+	a := &nameAnalyzer{reporter: reporter{ps, errors.Name}, names: ps.names, typeConstructors: make(map[string]bool)}
+	for _, builtin := range builtinNames {
+		a.names.DeclareName(builtin)
+		a.typeConstructors[builtin] = true
+	}
+
+	if h, just := ps.ast.header.Break(); just {
+		a.header(h)
+	}
+
+	if b, just := ps.ast.body.Break(); just {
+		a.declarationGroup(bodyElements(b))
+	}
+}
+
+func bodyElements(b body) []mainElement {
+	elems := make([]mainElement, 0, b.Len())
+	for _, elem := range b.Elements() {
+		elems = append(elems, data.Cases(elem.Either, (def).asMainElement, (visibleBodyElement).asMainElement))
+	}
+	return elems
+}
+
+// returns the string a name-like node represents
+func nameString(n interface{ Extract() api.Token }) string { return n.Extract().String() }
+
+func isLowerName(n name) bool { return common.Is_camelCase2(n.Extract()) }
+
+func isUpperName(n name) bool { return common.Is_PascalCase2(n.Extract()) }
+
+// returns true iff `n` is neither a lowercase nor an uppercase identifier, e.g., `+`
+func isInfixName(n name) bool { return !isLowerName(n) && !isUpperName(n) }
+
+// declares the package qualifiers and selected symbols of each import
+func (a *nameAnalyzer) header(h header) {
+	for _, statement := range h.Snd().Elements() {
+		for _, imp := range statement.Snd().Elements() {
+			a.packageImport(imp)
+		}
+	}
+}
+
+func (a *nameAnalyzer) packageImport(imp packageImport) {
+	path := strings.Trim(nameString(imp.Fst()), "\"")
+	qualifier := path[strings.LastIndexByte(path, '/')+1:]
+
+	if sel, just := imp.Snd().Break(); just {
+		alias, mSymbols, isUsing := sel.Break()
+		if !isUsing {
+			qualifier = nameString(alias)
+		} else if symbols, just := mSymbols.Break(); !just {
+			a.openImport = true
+		} else {
+			for _, n := range symbols.Elements() {
+				a.names.Declare(n.Extract())
+			}
+		}
+	}
+
+	a.names.DeclareName(qualifier)
+}
+
+// declares each name of a group, then resolves each element
+func (a *nameAnalyzer) declarationGroup(elems []mainElement) {
+	// names declared only by definitions--these can be declared again by a typing
+	defined := make(map[string]bool)
+	for _, elem := range elems {
+		a.declareElement(elem, defined)
+	}
+
+	for _, elem := range elems {
+		a.mainElement(elem)
+	}
+}
+
+func (a *nameAnalyzer) declare(n name, defined map[string]bool) {
+	x := nameString(n)
+	if a.names.Declare(n.Extract()) {
+		return
+	} else if defined[x] {
+		delete(defined, x)
+		return
+	}
+	a.errorAt(DuplicateDefinition+": "+x, n)
+}
+
+func (a *nameAnalyzer) declareElement(elem mainElement, defined map[string]bool) {
+	switch e := elem.(type) {
+	case def:
+		if n, _, ok := definedName(e.pattern); ok && !a.names.Declared(nameString(n)) {
+			a.names.Declare(n.Extract())
+			defined[nameString(n)] = true
+		}
+	case typing:
+		a.declare(e.typing.Fst(), defined)
+	case typeDef:
+		a.declare(e.typedef.Fst().typing.Fst(), defined)
+		a.typeConstructors[nameString(e.typedef.Fst().typing.Fst())] = true
+		if constructors, _, isImpossible := e.typedef.Snd().Break(); !isImpossible {
+			for _, constructor := range constructors.Elements() {
+				a.declare(constructor.constructor.Fst(), defined)
+			}
+		}
+	case typeAlias:
+		a.declare(e.alias.Fst(), defined)
+		a.typeConstructors[nameString(e.alias.Fst())] = true
+	case specDef:
+		a.declare(name(e.specHead.Snd().Fst()), defined)
+		for _, member := range e.specBody.Elements() {
+			if _, ty, isTyping := member.Break(); isTyping {
+				a.declare(ty.typing.Fst(), defined)
+			}
+		}
+	case specInst:
+		// a named instance declares its name
+		if !e.target.IsNothing() {
+			a.declare(name(e.head.Snd().Fst()), defined)
+		}
+	}
+}
+
+// returns the name a definition defines along with the definition's parameters
+//
+// the name is either
+//   - the pattern itself, e.g., `x` in `x = 1`
+//   - the head of the pattern, e.g., `f` in `f x = x`
+//   - the infix name following the head of the pattern, e.g., `+` in `Zero + y = y`
+func definedName(pat pattern) (n name, params []pattern, ok bool) {
+	switch p := pat.(type) {
+	case name:
+		return p, nil, true
+	case patternEnclosed:
+		if p.Len() == 1 && !p.implicit {
+			n, ok = p.Head().(name)
+		}
+		return n, nil, ok
+	case patternApp:
+		head, args := p.Fst(), p.Snd().Elements()
+		if infix, isName := args[0].(name); isName && isInfixName(infix) {
+			return infix, append([]pattern{head}, args[1:]...), true
+		}
+		n, _, ok = definedName(head)
+		if ok && isUpperName(n) {
+			return n, nil, false // constructor application, not a definition head
+		}
+		return n, args, ok
+	}
+	return n, nil, false
+}
+
+func (a *nameAnalyzer) mainElement(elem mainElement) {
+	switch e := elem.(type) {
+	case def:
+		a.def(e, false)
+	case typing:
+		a.typing(e)
+	case typeDef:
+		a.typing(e.typedef.Fst())
+		if constructors, _, isImpossible := e.typedef.Snd().Break(); !isImpossible {
+			for _, constructor := range constructors.Elements() {
+				a.typeSig(constructor.constructor.Snd())
+			}
+		}
+		if deriving, just := e.deriving.Break(); just {
+			for _, c := range deriving.Elements() {
+				a.constrainer(c)
+			}
+		}
+	case typeAlias:
+		a.typeAlias(e)
+	case specDef:
+		a.specDef(e)
+	case specInst:
+		a.specInst(e)
+	case syntax:
+		a.syntax(e)
+	}
+}
+
+// aliases can only alias type constructors, e.g.,
+//
+//	```
+//	alias Bool = bool.Bool -- ok, `bool.Bool` is a (qualified) type constructor
+//	alias Nats = List Nat  -- ok, `List` is a type constructor
+//	alias Z = Zero         -- illegal, `Zero` is a data constructor
+//	```
+func (a *nameAnalyzer) typeAlias(alias typeAlias) {
+	rhs := alias.alias.Snd()
+	a.typeSig(rhs)
+	if !a.aliasesTypeConstructor(rhs) {
+		a.errorAt(IllegalAliasTarget, rhs)
+	}
+}
+
+// returns true iff the head of `ty` is a type constructor or is an uppercase name whose meaning is
+// unknown (i.e., it's qualified, unbound, or possibly imported)
+func (a *nameAnalyzer) aliasesTypeConstructor(ty typ) bool {
+	switch x := ty.(type) {
+	case name:
+		// unbound, uppercase names are either reported elsewhere or assumed to be imported; unbound,
+		// lowercase names are type variables
+		_, bound := a.names.Lookup(nameString(x))
+		return a.typeConstructors[nameString(x)] || !bound && !isLowerName(x)
+	case appType:
+		if member, isQualified := qualifiedMember(x); isQualified {
+			// the meaning of a qualified name is unknown, but it must name a type, e.g., `bool.Bool`
+			return common.Is_PascalCase2(member.Extract())
+		}
+		return a.aliasesTypeConstructor(x.Fst())
+	case enclosedType:
+		return !x.implicit && a.aliasesTypeConstructor(x.typ)
+	}
+	return false
+}
+
+// returns the member a qualified name accesses, e.g., `Bool` in `bool.Bool`, or false if `ty` isn't
+// a qualified name
+func qualifiedMember(ty appType) (member access, isQualified bool) {
+	for _, arg := range ty.Snd().Elements() {
+		acc, isAccess := arg.(access)
+		if !isAccess {
+			break
+		}
+		member, isQualified = acc, true
+	}
+	return member, isQualified
+}
+
+// resolves a name, reporting it if it's unbound
+func (a *nameAnalyzer) resolve(n name) {
+	x := nameString(n)
+	if _, found := a.names.Lookup(x); found || a.openImport {
+		return
+	}
+	a.errorAt(UnboundName+": "+x, n)
+}
+
+// binds a name in the current scope
+func (a *nameAnalyzer) bind(n name) {
+	x := nameString(n)
+	if a.names.Declared(x) {
+		a.errorAt(DuplicateBinding+": "+x, n)
+		return
+	}
+	if !a.quiet && a.names.Shadows(x) {
+		a.warningAt(ShadowedName+": "+x, n)
+	}
+	a.names.Declare(n.Extract())
+}
+
+func identAsName(id ident) name {
+	return data.Cases(id, func(l lowerIdent) name { return name(l) }, func(u upperIdent) name { return name(u) })
+}
+
+// defs inside spec instances and where clauses define names declared elsewhere, so their names are
+// resolved when `resolveName` is true
+func (a *nameAnalyzer) def(d def, resolveName bool) {
+	a.names.Enter()
+	defer a.names.Exit()
+
+	n, params, ok := definedName(d.pattern)
+	if !ok {
+		a.bindPattern(d.pattern)
+	} else {
+		if resolveName {
+			a.resolve(n)
+		}
+		for _, param := range params {
+			a.bindPattern(param)
+		}
+	}
+	a.defBody(d.defBody)
+}
+
+func (a *nameAnalyzer) defBody(body defBody) {
+	_, possible, isPossible := body.Break()
+	if !isPossible {
+		return
+	}
+
+	a.names.Enter()
+	defer a.names.Exit()
+
+	if where, just := possible.Snd().Break(); just {
+		a.declarationGroup(where.Elements())
+	}
+
+	with, e, isExpr := possible.Fst().Break()
+	if isExpr {
+		a.expr(e)
+	} else {
+		a.withClause(with)
+	}
+}
+
+func (a *nameAnalyzer) withClause(with withClause) {
+	a.pattern(with.Fst())
+	for _, arm := range with.Snd().Elements() {
+		a.names.Enter()
+		// the arms of a with clause repeat the patterns of the definition they refine, so rebinding is
+		// expected
+		quiet := a.quiet
+		a.quiet = true
+		lhs, both, isBoth := arm.Fst().Break()
+		if isBoth {
+			a.bindPattern(both.Fst())
+			a.bindPattern(both.Snd())
+		} else {
+			a.bindPattern(lhs)
+		}
+		a.quiet = quiet
+		a.defBody(arm.Snd())
+		a.names.Exit()
+	}
+}
+
+// binds each variable of a pattern in the current scope, resolving each constructor
+func (a *nameAnalyzer) bindPattern(pat pattern) {
+	switch p := pat.(type) {
+	case name:
+		if isLowerName(p) {
+			a.bind(p)
+		} else {
+			a.resolve(p)
+		}
+	case patternApp:
+		a.bindPattern(p.Fst())
+		for _, arg := range p.Snd().Elements() {
+			a.bindPattern(arg)
+		}
+	case patternEnclosed:
+		for _, elem := range p.Elements() {
+			a.bindPattern(elem)
+		}
+	}
+}
+
+// resolves each name of a pattern without binding any of them
+func (a *nameAnalyzer) pattern(pat pattern) {
+	switch p := pat.(type) {
+	case name:
+		a.resolve(p)
+	case patternApp:
+		if a.qualified(p.Fst(), p.Snd().Head()) {
+			return
+		}
+		a.pattern(p.Fst())
+		for _, arg := range p.Snd().Elements() {
+			a.pattern(arg)
+		}
+	case patternEnclosed:
+		for _, elem := range p.Elements() {
+			a.pattern(elem)
+		}
+	}
+}
+
+// returns true iff `head` followed by `next` is a qualified name, e.g., `bool.Bool`
+//
+// qualified names are not resolved since the symbols of other packages are unknown
+func (a *nameAnalyzer) qualified(head, next api.Node) bool {
+	n, isName := head.(name)
+	if _, isAccess := next.(access); !isName || !isAccess {
+		return false
+	}
+	a.resolve(n)
+	return true
+}
+
+func (a *nameAnalyzer) expr(e expr) {
+	switch x := e.(type) {
+	case name:
+		a.resolve(x)
+	case exprApp:
+		if !a.qualified(x.Fst(), x.Snd().Head()) {
+			a.expr(x.Fst())
+		}
+		for _, arg := range x.Snd().Elements() {
+			a.expr(arg)
+		}
+	case lambdaAbstraction:
+		a.names.Enter()
+		for _, binder := range x.Fst().Elements() {
+			if b, _, isWildcard := binder.Either.Break(); !isWildcard {
+				a.binder(b)
+			}
+		}
+		a.expr(x.Snd())
+		a.names.Exit()
+	case letExpr:
+		a.names.Enter()
+		a.letBinding(x.Fst())
+		a.expr(x.Snd())
+		a.names.Exit()
+	case caseExpr:
+		a.pattern(x.Fst())
+		for _, arm := range x.Snd().Elements() {
+			a.names.Enter()
+			a.bindPattern(arm.Fst())
+			a.defBody(arm.Snd())
+			a.names.Exit()
+		}
+	}
+}
+
+func (a *nameAnalyzer) binder(b binder) {
+	data.Cases(b, func(id ident) api.Node { a.bind(identAsName(id)); return id }, func(pat pattern) api.Node { a.bindPattern(pat); return pat })
+}
+
+// binds every member of a let binding group, then resolves each bound expression
+//
+// let binding groups are recursive, so each member can refer to any other member of its group
+func (a *nameAnalyzer) letBinding(group letBinding) {
+	members := group.Elements()
+	for _, member := range members {
+		bound, ty, isTyping := member.Break()
+		if isTyping {
+			a.bind(ty.Fst().typing.Fst())
+		} else {
+			a.binder(bound.Fst())
+		}
+	}
+
+	for _, member := range members {
+		bound, ty, isTyping := member.Break()
+		if !isTyping {
+			a.expr(bound.Snd())
+			continue
+		}
+		a.typeSig(ty.Fst().typing.Snd())
+		if e, just := ty.Snd().Break(); just {
+			a.expr(e)
+		}
+	}
+}
+
+func (a *nameAnalyzer) typing(ty typing) {
+	a.typeSig(ty.typing.Snd())
+}
+
+// resolves the names of a type signature in a new scope
+func (a *nameAnalyzer) typeSig(ty typ) {
+	a.names.Enter()
+	a.typ(ty)
+	a.names.Exit()
+}
+
+// resolves the names of a type
+//
+// lowercase names that cannot be resolved are implicitly bound type variables and are not reported
+func (a *nameAnalyzer) typ(ty typ) {
+	switch x := ty.(type) {
+	case name:
+		if !isLowerName(x) {
+			a.resolve(x)
+		}
+	case functionType:
+		// dependent binders, e.g., `(x : A) -> B x`, are in scope for the rest of the function type
+		a.typ(x.Fst())
+		a.bindTypeTerms(x.Fst())
+		a.typ(x.Snd())
+	case forallType:
+		for _, id := range x.Fst().Elements() {
+			a.bind(identAsName(id))
+		}
+		a.typ(x.Snd())
+	case enclosedType:
+		a.typ(x.typ)
+	case appType:
+		if !a.qualified(x.Fst(), x.Snd().Head()) {
+			a.typ(x.Fst())
+		}
+		for _, arg := range x.Snd().Elements() {
+			a.typ(arg)
+		}
+	case innerTypeTerms:
+		for _, term := range x.Elements() {
+			a.typ(term)
+		}
+	case innerTyping:
+		a.typ(x.typing.Snd())
+	case implicitTyping:
+		a.typ(x.Fst())
+		a.expr(x.Snd().Extract())
+	case constrainedType:
+		a.constraint(x.Fst())
+		a.typ(x.Snd())
+	case lambdaAbstraction:
+		a.expr(x)
+	}
+}
+
+// binds the terms of an enclosed typing, e.g., `x` in `(x : A)` and `{x : A := a}`
+func (a *nameAnalyzer) bindTypeTerms(ty typ) {
+	enclosed, ok := ty.(enclosedType)
+	if !ok {
+		return
+	}
+
+	var inner innerTyping
+	switch x := enclosed.typ.(type) {
+	case innerTyping:
+		inner = x
+	case implicitTyping:
+		inner = x.Fst()
+	default:
+		return
+	}
+
+	for _, term := range inner.typing.Fst().Elements() {
+		if n, isName := term.(name); isName {
+			a.bind(n)
+		}
+	}
+}
+
+func (a *nameAnalyzer) constraint(c constraint) {
+	switch x := c.(type) {
+	case constraintUnverified:
+		a.typ(x.Extract())
+	case constraintVerified:
+		a.constraintVerified(x)
+	}
+}
+
+func (a *nameAnalyzer) constraintVerified(c constraintVerified) {
+	for _, elem := range c.Elements() {
+		// e.g., `Eq` in `(Eq, Ord a)`
+		for _, id := range elem.Fst().Elements() {
+			a.resolve(name(id))
+		}
+		a.constrainer(elem.Snd())
+	}
+}
+
+func (a *nameAnalyzer) constrainer(c constrainer) {
+	a.resolve(name(c.Fst()))
+	a.typePattern(c.Snd())
+}
+
+// resolves the names of a pattern appearing in a type-level position
+//
+// like types, lowercase names that cannot be resolved are implicitly bound type variables
+func (a *nameAnalyzer) typePattern(pat pattern) {
+	switch p := pat.(type) {
+	case name:
+		if !isLowerName(p) {
+			a.resolve(p)
+		}
+	case patternApp:
+		if a.qualified(p.Fst(), p.Snd().Head()) {
+			return
+		}
+		a.typePattern(p.Fst())
+		for _, arg := range p.Snd().Elements() {
+			a.typePattern(arg)
+		}
+	case patternEnclosed:
+		for _, elem := range p.Elements() {
+			a.typePattern(elem)
+		}
+	}
+}
+
+func (a *nameAnalyzer) specHead(head specHead) {
+	if c, just := head.Fst().Break(); just {
+		a.constraintVerified(c)
+	}
+	a.typePattern(head.Snd().Snd())
+}
+
+func (a *nameAnalyzer) specDef(spec specDef) {
+	a.names.Enter()
+	defer a.names.Exit()
+
+	a.specHead(spec.specHead)
+	if dep, just := spec.dependency.Break(); just {
+		a.typePattern(dep)
+	}
+	a.specMembers(spec.specBody)
+	if requiring, just := spec.requiring.Break(); just {
+		for _, d := range requiring.Elements() {
+			a.def(d, true)
+		}
+	}
+}
+
+func (a *nameAnalyzer) specInst(inst specInst) {
+	a.names.Enter()
+	defer a.names.Exit()
+
+	a.specHead(inst.head)
+	if target, just := inst.target.Break(); just {
+		a.constrainer(target)
+	} else {
+		a.resolve(name(inst.head.Snd().Fst()))
+	}
+	a.specMembers(inst.body)
+}
+
+func (a *nameAnalyzer) specMembers(body specBody) {
+	for _, member := range body.Elements() {
+		d, ty, isTyping := member.Break()
+		if isTyping {
+			a.typing(ty)
+		} else {
+			a.def(d, true)
+		}
+	}
+}
+
+func (a *nameAnalyzer) syntax(s syntax) {
+	a.names.Enter()
+	defer a.names.Exit()
+
+	// the variables a rule binds are renamed when it's expanded (see `substitution`), so they never
+	// shadow anything where it's used
+	quiet := a.quiet
+	a.quiet = true
+	for _, symbol := range s.rule.Fst().Elements() {
+		if id, _, isKeyword := symbol.Break(); !isKeyword {
+			a.bind(identAsName(id.id))
+		}
+	}
+	a.expr(s.rule.Snd())
+	a.quiet = quiet
+}
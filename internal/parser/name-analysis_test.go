@@ -0,0 +1,66 @@
+//go:build test
+// +build test
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/petersalex27/yew/api/util"
+	"github.com/petersalex27/yew/internal/lexer"
+)
+
+func TestAnalyzeNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		errors   int
+		warnings int
+	}{
+		{"bound", "x = x", 0, 0},
+		{"unbound", "x = y", 1, 0},
+		{"open import", "import \"base\" using _\n\nx = y", 0, 0},
+		{"selected import", "import \"base\" using (True)\n\nx = True", 0, 0},
+		{"qualified", "import \"base/bool\"\n\nx = bool.True", 0, 0},
+		{"typing and def", "x : Type\nx = x", 0, 0},
+		{"duplicate typing", "x : Type\nx : Type", 1, 0},
+		{"duplicate constructor", "A : Type where (\n  B : A\n  B : A\n)", 1, 0},
+		{"duplicate binding", "f x x = x", 1, 0},
+		{"shadowing", "f x = \\x => x", 0, 1},
+		{"type variables", "id : a -> a", 0, 0},
+		{"unbound type", "id : A -> A", 2, 0},
+		{"dependent binder", "f : {n : Type} -> n", 0, 0},
+		{"where clause", "f x = y where (\n  y = x\n)", 0, 0},
+		{"let", "f x = let y := x in y", 0, 0},
+		{"syntax", "syntax `if` c `then` t `else` e = c t e", 0, 0},
+		{"syntax variable named like a definition", "c = c\n\nsyntax `twice` c = c", 0, 0},
+		{"spec and inst", "A : Type where (\n  B : A\n)\n\nspec S s where (\n  f : s -> s\n)\n\ninst S A where (\n  f B = B\n)", 0, 0},
+		{"alias type", "A : Type where (\n  B : A\n)\n\nalias C = A", 0, 0},
+		{"alias type app", "A : Type -> Type where (\n  B : A a\n)\n\nalias C = A Type", 0, 0},
+		{"alias qualified", "import \"base/bool\"\n\nalias Bool = bool.Bool", 0, 0},
+		{"alias qualified function", "import \"base/bool\"\n\nalias Not = bool.not", 1, 0},
+		{"alias alias", "A : Type where (\n  B : A\n)\n\nalias C = A\nalias D = C", 0, 0},
+		{"alias constructor", "A : Type where (\n  B : A\n)\n\nalias C = B", 1, 0},
+		{"alias function", "f : Type\n\nalias C = f", 1, 0},
+		{"alias type variable", "alias C = a", 1, 0},
+		{"alias spec member", "spec S s where (\n  g : s\n)\n\nalias C = g", 1, 0},
+		{"inst of unbound spec", "A : Type where (\n  B : A\n)\n\ninst S A where (\n  f B = B\n)", 2, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := Init(lexer.Init(util.StringSource(test.source)))
+			if Run(p) == nil {
+				t.Fatalf("unexpected parse failure: %v", p.Errors())
+			}
+
+			Analyze(p)
+			if len(p.Errors()) != test.errors {
+				t.Errorf("expected %d error(s), got %d: %v", test.errors, len(p.Errors()), p.Errors())
+			}
+			if len(p.Warnings()) != test.warnings {
+				t.Errorf("expected %d warning(s), got %d: %v", test.warnings, len(p.Warnings()), p.Warnings())
+			}
+		})
+	}
+}
@@ -360,6 +360,8 @@ func parsePatternBoundBody(p parser, bindingTokenType token.Type) data.Either[da
 	} else if matchCurrentWith(p) {
 		construct := fun.Compose(data.Ok, data.Inl[expr, withClause])
 		possibleLeft = data.Cases(parseWithClause(p), data.PassErs[data.Either[withClause, expr]], construct)
+	} else {
+		return data.Fail[defBody](ExpectedDefBody, p.current())
 	}
 
 	return runCases(p, fun.Constant[parser](possibleLeft), passParseErs[defBody], runDefBodyWhereClause)
@@ -549,6 +549,30 @@ func TestParseDefBody(t *testing.T) {
 	}
 }
 
+// a def body that's neither impossible nor starts with "=" or "with" is an error
+func TestParseDefBodyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []api.Token
+	}{
+		{"no binding token", []api.Token{id_x_tok}},
+		{"other binding token", []api.Token{colon, id_x_tok}},
+		{"end of input", []api.Token{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			es, actual, isActual := parseDefBody(initTestParser(test.input)).Break()
+			if isActual {
+				t.Fatalf("expected %q, got \n%s\n", ExpectedDefBody, sprintTree(actual))
+			}
+			if e, just := es.Head().Break(); !just || e.Msg() != ExpectedDefBody {
+				t.Errorf("expected %q, got \n%s\n", ExpectedDefBody, sprintTree(es))
+			}
+		})
+	}
+}
+
 // rule:
 //
 //	```
@@ -48,6 +48,11 @@ func parseTypeHelper(p parser, enclosed bool) data.Either[data.Ers, typ] {
 //
 // typeHelper handles deciding b/w a forall type and a type tail
 func parseType(p parser, enclosed bool) data.Either[data.Ers, typ] {
+	// a parenthesized typing is a type term, e.g., `(x : A)` in `(x : A) -> B`
+	if lookaheadEnclosedTyping(p) {
+		return parseTypeHelper(p, enclosed)
+	}
+
 	lparen, found := getKeywordAtCurrent(p, token.LeftParen, dropAfter)
 	if !found {
 		// not enclosed in this call, parse with inherited `enclosed` value
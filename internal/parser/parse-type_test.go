@@ -49,6 +49,36 @@ func TestParseType(t *testing.T) {
 			[]api.Token{lparen, newline, id_x_tok, newline, rparen},
 			typ_x,
 		},
+		{
+			"enclosed typing",
+			// (x : x) -> x
+			[]api.Token{lparen, id_x_tok, colon, id_x_tok, rparen, arrow, id_x_tok},
+			makeFunc(enclosedTypingNode, typ_x),
+		},
+		{
+			"enclosed typing - newline",
+			// (\n x : x) -> x
+			[]api.Token{lparen, newline, id_x_tok, colon, id_x_tok, rparen, arrow, id_x_tok},
+			makeFunc(enclosedTypingNode, typ_x),
+		},
+		{
+			"enclosed typing sequence",
+			// (x, x : x) -> x
+			[]api.Token{lparen, id_x_tok, comma, id_x_tok, colon, id_x_tok, rparen, arrow, id_x_tok},
+			makeFunc(enclosedTypingSeqNode, typ_x),
+		},
+		{
+			"enclosed once typing",
+			// (once x : x) -> x
+			[]api.Token{lparen, onceTok, id_x_tok, colon, id_x_tok, rparen, arrow, id_x_tok},
+			makeFunc(enclosedOnceTypingNode, typ_x),
+		},
+		{
+			"enclosed erase typing",
+			// (erase x : x) -> x
+			[]api.Token{lparen, eraseTok, id_x_tok, colon, id_x_tok, rparen, arrow, id_x_tok},
+			makeFunc(enclosedEraseTypingNode, typ_x),
+		},
 		{
 			"forall type - 000",
 			[]api.Token{forall, id_x_tok, in, id_x_tok},
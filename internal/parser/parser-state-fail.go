@@ -10,7 +10,11 @@ type ParserStateFail struct{ bad *ParserState }
 func (p *ParserStateFail) dropNewlines() { /* noop */ }
 
 func (p *ParserStateFail) srcCode() api.SourceCode {
-	return p.bad.scanner.SrcCode()
+	return p.bad.srcCode()
+}
+
+func (p *ParserStateFail) locate(start, end int) (api.SourceCode, int, int) {
+	return p.bad.locate(start, end)
 }
 
 func (p *ParserStateFail) Pos() (int, int) {
@@ -30,3 +34,10 @@ func (p *ParserStateFail) report(e error, _ bool) parser {
 	p.bad.AddError(e)
 	return p
 }
+
+// just adds the warning
+func (p *ParserStateFail) warn(w error) { p.bad.warn(w) }
+
+func (p *ParserStateFail) Errors() []error { return p.bad.Errors() }
+
+func (p *ParserStateFail) Warnings() []error { return p.bad.Warnings() }
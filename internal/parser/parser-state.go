@@ -4,30 +4,46 @@ import (
 	"github.com/petersalex27/yew/api"
 	"github.com/petersalex27/yew/api/token"
 	"github.com/petersalex27/yew/api/util"
+	"github.com/petersalex27/yew/internal/errors"
+	"github.com/petersalex27/yew/internal/source"
+	"github.com/petersalex27/yew/internal/symbol"
 )
 
 type state struct {
-	scanner      api.ScannerPlus
+	scanner      api.Scanner
 	tokens       []api.Token
 	tokenCounter int
 	errors       []error
 	warnings     []error
-	//names        *symbol.Table
+	names        *symbol.Table
 }
 
 // State for parsing
 type ParserState struct {
 	state
 	ast yewSource
+	// sources `Include`d in the AST, in the order they were included
+	included []includedSource
+	// number of elements at the front of the AST's body that came from included sources
+	includedElems int
 }
 
-func createState(scanner api.ScannerPlus) state {
+// a source `Include`d in the AST of another; the positions of its tokens are offset by `offset`, so
+// they don't overlap the positions of any other source in the AST
+type includedSource struct {
+	src    api.SourceCode
+	offset int
+}
+
+var _ api.Parser = (*ParserState)(nil)
+
+func createState(scanner api.Scanner) state {
 	return state{
 		scanner:  scanner,
 		tokens:   nil,
 		errors:   make([]error, 0),
 		warnings: make([]error, 0),
-		//names:    symbol.New(),
+		names:    symbol.New(),
 	}
 }
 
@@ -37,8 +53,43 @@ func (p *ParserState) dropNewlines() {
 	}
 }
 
+// returns the source code of the scanner if it has any, otherwise, returns empty source code
 func (p *ParserState) srcCode() api.SourceCode {
-	return p.scanner.SrcCode()
+	if scanner, ok := p.scanner.(interface{ SrcCode() api.SourceCode }); ok {
+		return scanner.SrcCode()
+	}
+	return (source.SourceCode{}).Set(util.EmptySource())
+}
+
+// returns the source the positions `start` and `end` are in--either the parser's own or one
+// included in its AST--along with the positions within that source
+func (p *ParserState) locate(start, end int) (api.SourceCode, int, int) {
+	for i := len(p.included) - 1; i >= 0; i-- {
+		if inc := p.included[i]; start >= inc.offset {
+			return inc.src, start - inc.offset, end - inc.offset
+		}
+	}
+	return p.srcCode(), start, end
+}
+
+// returns the offset of the next source included in the AST, i.e., a position past the end of every
+// source already in it
+func (p *ParserState) nextOffset() int {
+	if n := len(p.included); n != 0 {
+		last := p.included[n-1]
+		return last.offset + len(last.src.String()) + 1
+	}
+	return len(p.srcCode().String()) + 1
+}
+
+// offsets the position of each of the parser's tokens by `offset`
+func (p *ParserState) shiftTokens(offset int) {
+	for i, tok := range p.tokens {
+		if t, isToken := tok.(token.Token); isToken {
+			t.Start, t.End = t.Start+offset, t.End+offset
+			p.tokens[i] = t
+		}
+	}
 }
 
 func (p *ParserState) Pos() (int, int) {
@@ -70,7 +121,12 @@ func (parser *ParserState) Ast() api.Node {
 	return parser.ast
 }
 
+// tokenizes the scanner's input; noop if the parser already has tokens
 func (parser *ParserState) load() bool {
+	if parser.tokens != nil {
+		return true
+	}
+
 	tokens, errorToken := util.Tokenize(parser.scanner, nil)
 	if errorToken != nil {
 		parser.AddError((*errorToken).Error())
@@ -98,7 +154,7 @@ func (parser *ParserState) Parse() bool {
 	}
 
 	parser.Run()
-	return len(parser.Errors()) == 0
+	return len(parser.errors) == 0
 }
 
 func (parser *ParserState) AddError(err error) {
@@ -113,13 +169,27 @@ func (parser *ParserState) Errors() []error {
 	return parser.errors
 }
 
+func (parser *ParserState) warn(warning error) {
+	parser.warnings = append(parser.warnings, warning)
+}
+
+// ensure that the warnings slice is never nil when needed
+func (parser *ParserState) Warnings() []error {
+	if parser.warnings == nil {
+		parser.warnings = make([]error, 0)
+	}
+	return parser.warnings
+}
+
 func (parser *ParserState) ReferenceScanner() *api.Scanner {
-	s := api.Scanner(parser.scanner)
-	return &s
+	return &parser.scanner
 }
 
 func (*ParserState) Clear() api.Parser {
-	return &ParserState{}
+	return &ParserState{
+		state: createState(nil),
+		ast:   makeEmptyYewSource(),
+	}
 }
 
 func (parser *ParserState) current() api.Token {
@@ -135,7 +205,35 @@ func (parser *ParserState) advance() {
 	}
 }
 
+// runs the parser on its loaded tokens
+//
+// SEE: `Run`
 func (p *ParserState) Run() {
-	// TODO: Implement
-	panic("not implemented")
+	Run(p)
+}
+
+// reports the errors and warnings of a pass over the AST of `p`; it's embedded in the state of each
+// pass
+type reporter struct {
+	p parser
+	// creates the errors the pass reports, e.g., `errors.Type`
+	kind func(s api.SourceCode, msg string, start, end int) error
+}
+
+func (r reporter) errorAt(msg string, n api.Positioned) {
+	src, start, end := r.p.locate(n.Pos())
+	r.p.report(r.kind(src, msg, start, end), false)
+}
+
+// reports an error at `n` with a note pointing to the related `m`
+func (r reporter) errorWithNote(msg string, n api.Positioned, note string, m api.Positioned) {
+	src, start, end := r.p.locate(n.Pos())
+	err := r.kind(src, msg, start, end)
+	src, start, end = r.p.locate(m.Pos())
+	r.p.report(errors.WithNote(err, src, note, start, end), false)
+}
+
+func (r reporter) warningAt(msg string, n api.Positioned) {
+	src, start, end := r.p.locate(n.Pos())
+	r.p.warn(errors.Warning(src, msg, start, end))
 }
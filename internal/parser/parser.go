@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/petersalex27/yew/api"
 	"github.com/petersalex27/yew/common/data"
+	"github.com/petersalex27/yew/internal/core"
 )
 
 // parser is the interface for parsing source code
@@ -11,8 +14,16 @@ type parser interface {
 	api.Positioned
 	// return the source code
 	srcCode() api.SourceCode
+	// return the source the positions are in (see `Include`) and the positions within it
+	locate(start, end int) (api.SourceCode, int, int)
 	// add the error, and if fatal, then return a fail state
 	report(error, bool) parser
+	// add the warning
+	warn(error)
+	// return all errors reported so far
+	Errors() []error
+	// return all warnings reported so far
+	Warnings() []error
 	// return token at the current position
 	current() api.Token
 	// advance the parser to the next token
@@ -34,14 +45,191 @@ func Init(scanner api.ScannerPlus) parser {
 	return ps
 }
 
-// Run an initialized parser
+// Run an initialized parser, returning the root of the AST (an api.SourceRoot) on success and nil
+// on failure
 //
 // SEE: `Init`
 func Run(p parser) api.Node {
-	parseYewSource(p)
+	ps, ok := parseYewSource(p).(*ParserState)
+	if !ok || len(ps.errors) != 0 {
+		return nil
+	}
+	return ps.ast
+}
+
+// Include parses the source of `scanner` as a module of its own, then adds the elements of its body
+// to the body of a successfully run parser's AST (after those of any source included before it but
+// before the parser's own), returning the root of the AST on success and nil on failure
+//
+// the included source's header and annotations are dropped; errors in it are reported against it
+//
+// SEE: `Run`
+func Include(p parser, scanner api.ScannerPlus) api.Node {
+	return include(p, scanner, false)
+}
+
+// Append parses the source of `scanner` like `Include`, but adds the elements of its body after
+// those of the parser's own, e.g., so an expression can be checked against the source it's
+// evaluated in
+//
+// SEE: `Include`
+func Append(p parser, scanner api.ScannerPlus) api.Node {
+	return include(p, scanner, true)
+}
+
+// parses the source of `scanner` as a module of its own, adding the elements of its body to the
+// body of `p` either before the parser's own elements or, if `last` is true, after them
+func include(p parser, scanner api.ScannerPlus, last bool) api.Node {
+	ps, ok := p.(*ParserState)
+	if !ok || len(ps.errors) != 0 {
+		return nil
+	}
+	q := Init(scanner)
+	inc, isState := q.(*ParserState)
+	if !isState {
+		ps.errors = append(ps.errors, q.Errors()...)
+		return nil
+	}
+
+	offset := ps.nextOffset()
+	inc.shiftTokens(offset)
+	inc.included = []includedSource{{inc.srcCode(), offset}}
+	root := Run(inc)
+	ps.warnings = append(ps.warnings, inc.Warnings()...)
+	if root == nil {
+		ps.errors = append(ps.errors, inc.Errors()...)
+		return nil
+	}
+	ps.included = append(ps.included, inc.included...)
+
+	own := bodyOf(ps)
+	included := bodyOf(inc)
+	at := ps.includedElems // index the included elements are inserted at
+	if last {
+		at = len(own)
+	} else {
+		ps.includedElems += len(included)
+	}
+	elems := data.Nil[bodyElement](len(own) + len(included))
+	for _, elem := range own[:at] {
+		elems = elems.Snoc(elem)
+	}
+	for _, elem := range included {
+		elems = elems.Snoc(elem)
+	}
+	for _, elem := range own[at:] {
+		elems = elems.Snoc(elem)
+	}
+	ps.ast.body = data.Just(body{elems})
+	return ps.ast
+}
+
+// returns the elements of the body of the AST of `ps`
+func bodyOf(ps *ParserState) []bodyElement {
+	if b, just := ps.ast.body.Break(); just {
+		return b.Elements()
+	}
 	return nil
 }
 
+// Definitions returns the source of the body of a successfully run parser's AST, i.e., its source
+// without its module header or the annotations following its body, e.g., so the definitions of a
+// module can be added to other source
+//
+// SEE: `Include`
+func Definitions(p parser) string {
+	ps, isState := p.(*ParserState)
+	if !isState {
+		return ""
+	}
+	elems := bodyOf(ps)
+	if len(elems) == 0 {
+		return ""
+	}
+	start, _ := elems[0].Pos()
+	_, end := elems[len(elems)-1].Pos()
+	src, start, end := ps.locate(start, end)
+	return strings.TrimSpace(src.String()[start:end]) + "\n"
+}
+
+// Validate the annotations of a successfully run parser's AST, then expand the uses of syntax rules
+// in it and re-associate its infix operator applications by fixity, returning the root of the AST
+// (now with each use rewritten to its rule's right-hand side and each operator applied prefix) on
+// success and nil on failure
+//
+// SEE: `Run`
+func Expand(p parser) api.Node {
+	ps, ok := p.(*ParserState)
+	if !ok || len(ps.errors) != 0 {
+		return nil
+	}
+	if validateAnnotations(ps); len(ps.errors) != 0 {
+		return nil
+	}
+	expandSyntax(ps)
+	resolveFixity(ps)
+	if len(ps.errors) != 0 {
+		return nil
+	}
+	return ps.ast
+}
+
+// Derive the instances requested by the deriving clauses of a successfully expanded parser's AST,
+// returning the root of the AST (now including the derived instances) on success and nil on failure
+//
+// SEE: `Expand`
+func Derive(p parser) api.Node {
+	ps, ok := p.(*ParserState)
+	if !ok || len(ps.errors) != 0 {
+		return nil
+	}
+	deriveInstances(ps)
+	if len(ps.errors) != 0 {
+		return nil
+	}
+	return ps.ast
+}
+
+// Analyze the names of a successfully derived parser's AST, returning true iff no errors were reported
+// during analysis
+//
+// SEE: `Derive`
+func Analyze(p parser) bool {
+	ps, ok := p.(*ParserState)
+	if !ok {
+		return false
+	}
+	n := len(ps.errors)
+	analyzeNames(ps)
+	return len(ps.errors) == n
+}
+
+// Check the types of a successfully analyzed parser's AST, returning true iff no errors were
+// reported during checking
+//
+// SEE: `Analyze`
+func Check(p parser) bool {
+	ps, ok := p.(*ParserState)
+	if !ok {
+		return false
+	}
+	n := len(ps.errors)
+	checkTypes(ps)
+	return len(ps.errors) == n
+}
+
+// Lower a successfully checked parser's AST into the core language, returning the lowered program
+// and true on success
+//
+// SEE: `Check`
+func Lower(p parser) (core.Program, bool) {
+	ps, ok := p.(*ParserState)
+	if !ok || len(ps.errors) != 0 {
+		return core.Program{}, false
+	}
+	return lowerProgram(ps), true
+}
+
 func then(p parser) bool {
 	origin := getOrigin(p)
 	p.dropNewlines()
@@ -0,0 +1,663 @@
+// =================================================================================================
+// syntax expansion: rewrites uses of `syntax` rules in expressions into the rules' right-hand sides
+// =================================================================================================
+
+package parser
+
+import (
+	"strconv"
+
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/api/token"
+	"github.com/petersalex27/yew/common/data"
+	"github.com/petersalex27/yew/internal/errors"
+)
+
+// a syntax rule, recognized by its first keyword, e.g., `if` for
+//
+//	```
+//	syntax `if` c `then` t `else` f = ifThenElse c t f
+//	```
+//
+// a use of a rule is an application whose spine spells out the rule: each keyword is a name equal
+// to the keyword, and each hole is one or more expressions between keywords, e.g.,
+// `if x == y then x else f y`
+type mixfix struct {
+	syntax
+	symbols []syntaxSymbol
+	// index of the first keyword of the rule; the holes before it are matched by everything before
+	// the keyword in a use
+	keyword int
+}
+
+// an expression that's already been expanded; it's only ever an element of a spine
+type expanded struct{ expr }
+
+type expander struct {
+	reporter
+	// syntax rules, keyed by their first keyword
+	rules map[string]mixfix
+	// names bound locally at the expression being expanded
+	locals []string
+	// counter for the fresh names given to the binders of expansions
+	fresh int
+}
+
+// expands each use of a syntax rule in the body of `ps`
+//
+// the right-hand side of a rule is not itself expanded, so rules can't be used to define other rules
+func expandSyntax(ps *ParserState) {
+	b, just := ps.ast.body.Break()
+	if !just {
+		return
+	}
+
+	x := &expander{reporter: reporter{ps, errors.Syntax}, rules: make(map[string]mixfix)}
+	elems := bodyElements(b)
+	for _, elem := range elems {
+		if s, isSyntax := elem.(syntax); isSyntax {
+			x.declare(s)
+		}
+	}
+	if len(x.rules) == 0 {
+		return
+	}
+
+	expandedElems := data.Nil[bodyElement](len(elems))
+	for _, elem := range elems {
+		expandedElems = expandedElems.Snoc(x.mainElement(elem).asBodyElement())
+	}
+	ps.ast.body = data.Just(body{expandedElems})
+}
+
+// records the rule `s`, reporting it if another rule already starts with its first keyword
+func (x *expander) declare(s syntax) {
+	r := mixfix{syntax: s, symbols: s.rule.Fst().Elements()}
+	for r.symbols[r.keyword].IsLeft() {
+		r.keyword++
+	}
+
+	kw := keywordString(r.symbols[r.keyword])
+	if other, found := x.rules[kw]; found {
+		x.errorWithNote(OverlappingSyntax+": `"+kw+"`", s, "overlaps this rule", other.syntax)
+		return
+	}
+	x.rules[kw] = r
+}
+
+func keywordString(symbol syntaxSymbol) string {
+	_, kw, _ := symbol.Break()
+	return kw.Extract().Extract().String()
+}
+
+// returns the rule `e` starts a use of, if any
+func (x *expander) rule(e expr) (mixfix, bool) {
+	n, isName := e.(name)
+	if !isName || x.local(nameString(n)) {
+		return mixfix{}, false
+	}
+	r, found := x.rules[nameString(n)]
+	return r, found
+}
+
+func (x *expander) local(s string) bool {
+	for i := len(x.locals) - 1; i >= 0; i-- {
+		if x.locals[i] == s {
+			return true
+		}
+	}
+	return false
+}
+
+// binds each variable of `pat` locally
+func (x *expander) bindPattern(pat pattern) {
+	switch p := pat.(type) {
+	case name:
+		if isLowerName(p) {
+			x.locals = append(x.locals, nameString(p))
+		}
+	case patternApp:
+		x.bindPattern(p.Fst())
+		for _, arg := range p.Snd().Elements() {
+			x.bindPattern(arg)
+		}
+	case patternEnclosed:
+		for _, elem := range p.Elements() {
+			x.bindPattern(elem)
+		}
+	}
+}
+
+func (x *expander) bindBinder(b binder) {
+	id, pat, isPattern := b.Break()
+	if isPattern {
+		x.bindPattern(pat)
+	} else {
+		x.bindPattern(identAsName(id))
+	}
+}
+
+func (x *expander) mainElement(elem mainElement) mainElement {
+	switch e := elem.(type) {
+	case def:
+		return x.def(e)
+	case specDef:
+		e.specBody = specBody{data.MapNonEmpty(x.specMember)(e.specBody.NonEmpty)}
+		if requiring, just := e.requiring.Break(); just {
+			e.requiring = data.Just(data.MapNonEmpty(x.def)(requiring))
+		}
+		return e
+	case specInst:
+		e.body = specBody{data.MapNonEmpty(x.specMember)(e.body.NonEmpty)}
+		return e
+	}
+	return elem
+}
+
+func (x *expander) specMember(member specMember) specMember {
+	return data.EitherMap(x.def, func(ty typing) typing { return ty })(member)
+}
+
+func (x *expander) def(d def) def {
+	mark := len(x.locals)
+	defer func() { x.locals = x.locals[:mark] }()
+
+	if _, params, ok := definedName(d.pattern); ok {
+		for _, param := range params {
+			x.bindPattern(param)
+		}
+	} else {
+		x.bindPattern(d.pattern)
+	}
+	d.defBody = x.defBody(d.defBody)
+	return d
+}
+
+func (x *expander) defBody(body defBody) defBody {
+	_, possible, isPossible := body.Break()
+	if !isPossible {
+		return body
+	}
+
+	mark := len(x.locals)
+	defer func() { x.locals = x.locals[:mark] }()
+
+	where, hasWhere := possible.Snd().Break()
+	if hasWhere {
+		for _, elem := range where.Elements() {
+			if ty, isTyping := elem.(typing); isTyping {
+				x.locals = append(x.locals, nameString(ty.typing.Fst()))
+			} else if d, isDef := elem.(def); isDef {
+				if n, _, ok := definedName(d.pattern); ok {
+					x.locals = append(x.locals, nameString(n))
+				}
+			}
+		}
+		where = whereClause{data.MapNonEmpty(x.mainElement)(where.NonEmpty)}
+	}
+
+	var rhs data.Either[withClause, expr]
+	if with, e, isExpr := possible.Fst().Break(); isExpr {
+		rhs = data.Inr[withClause](x.expr(e))
+	} else {
+		rhs = data.Inl[expr](x.withClause(with))
+	}
+	expandedBody := data.EMakePair[defBodyPossible](rhs, possible.Snd())
+	if hasWhere {
+		expandedBody = data.EMakePair[defBodyPossible](rhs, data.Just(where))
+	}
+	expandedBody.Position = possible.Position
+	return data.EInr[defBody](expandedBody)
+}
+
+func (x *expander) withClause(w withClause) withClause {
+	arms := data.MapNonEmpty(func(arm withClauseArm) withClauseArm {
+		mark := len(x.locals)
+		defer func() { x.locals = x.locals[:mark] }()
+
+		refined, pat, isRefined := withArm(arm)
+		if isRefined {
+			x.bindPattern(refined)
+		}
+		x.bindPattern(pat)
+		expandedArm := data.EMakePair[withClauseArm](arm.Fst(), x.defBody(arm.Snd()))
+		expandedArm.Position = arm.Position
+		return expandedArm
+	})(w.Snd().NonEmpty)
+	expandedWith := data.EMakePair[withClause](w.Fst(), withClauseArms{arms})
+	expandedWith.Position = w.Position
+	return expandedWith
+}
+
+func (x *expander) expr(e expr) expr {
+	switch y := e.(type) {
+	case name:
+		return x.spine([]expr{y}, y)
+	case exprApp:
+		return x.spine(append([]expr{y.Fst()}, y.Snd().Elements()...), y)
+	case lambdaAbstraction:
+		mark := len(x.locals)
+		for _, b := range y.Fst().Elements() {
+			if bound, _, isWildcard := b.Either.Break(); !isWildcard {
+				x.bindBinder(bound)
+			}
+		}
+		lambda := data.EMakePair[lambdaAbstraction](y.Fst(), x.expr(y.Snd()))
+		x.locals = x.locals[:mark]
+		lambda.Position = y.Position
+		return lambda
+	case letExpr:
+		mark := len(x.locals)
+		members := y.Fst().Elements()
+		for _, member := range members {
+			if bound, ty, isTyping := member.Break(); isTyping {
+				x.locals = append(x.locals, nameString(ty.Fst().typing.Fst()))
+			} else {
+				x.bindBinder(bound.Fst())
+			}
+		}
+		group := data.MapNonEmpty(x.bindingGroupMember)(y.Fst().NonEmpty)
+		let := data.EMakePair[letExpr](letBinding{group}, x.expr(y.Snd()))
+		x.locals = x.locals[:mark]
+		let.Position = y.Position
+		return let
+	case caseExpr:
+		arms := data.MapNonEmpty(func(arm caseArm) caseArm {
+			mark := len(x.locals)
+			defer func() { x.locals = x.locals[:mark] }()
+
+			x.bindPattern(arm.Fst())
+			expandedArm := data.EMakePair[caseArm](arm.Fst(), x.defBody(arm.Snd()))
+			expandedArm.Position = arm.Position
+			return expandedArm
+		})(y.Snd().NonEmpty)
+		c := data.EMakePair[caseExpr](y.Fst(), caseArms{arms})
+		c.Position = y.Position
+		return c
+	}
+	return e
+}
+
+func (x *expander) bindingGroupMember(member bindingGroupMember) bindingGroupMember {
+	bound, ty, isTyping := member.Break()
+	if !isTyping {
+		return data.Inl[data.Pair[typing, data.Maybe[expr]]](data.MakePair(bound.Fst(), x.expr(bound.Snd())))
+	}
+	return data.Inr[data.Pair[binder, expr]](data.MakePair(ty.Fst(), data.MaybeMap(x.expr)(ty.Snd())))
+}
+
+// expands the first use of a syntax rule in the application spine `elems` (positioned at `at`),
+// then the rest of the spine
+func (x *expander) spine(elems []expr, at api.Positioned) expr {
+	i := 0
+	for ; i < len(elems); i++ {
+		if _, found := x.rule(elems[i]); found {
+			break
+		}
+	}
+	if i == len(elems) {
+		return x.app(elems, at)
+	}
+
+	r, _ := x.rule(elems[i])
+	args, start, end, ambiguous, ok := x.match(r, elems, i, "")
+	if !ok {
+		x.errorWithNote(SyntaxMismatch+": `"+keywordString(r.symbols[r.keyword])+"`", at, "syntax rule is defined here", r.syntax)
+		return x.app(elems, at)
+	}
+
+	use := api.WeakenRangeOver(elems[start], elems[end-1])
+	if ambiguous {
+		x.errorAt(AmbiguousSyntax, use)
+	}
+	result := x.substitute(r, args, use)
+	if start == 0 && end == len(elems) {
+		return result
+	}
+	rest := append(append(elems[:start:start], expanded{result}), elems[end:]...)
+	return x.spine(rest, at)
+}
+
+// expands each of `elems`, returning their application
+func (x *expander) app(elems []expr, at api.Positioned) expr {
+	if len(elems) == 1 {
+		return x.element(elems[0])
+	}
+	args := make([]expr, len(elems)-1)
+	for i, elem := range elems[1:] {
+		args[i] = x.element(elem)
+	}
+	return data.EMakePair[exprApp](x.element(elems[0]), data.Construct(args[0], args[1:]...)).updatePosExpr(at)
+}
+
+func (x *expander) element(e expr) expr {
+	switch y := e.(type) {
+	case expanded:
+		return y.expr
+	case name:
+		return y
+	}
+	return x.expr(e)
+}
+
+// matches the rule `r` against `elems`, where `elems[i]` is the rule's first keyword; a hole
+// followed by a keyword is matched by the expressions up to that keyword, and a hole at the end of
+// the rule is matched by the expressions up to `stop` (or the end of `elems` when `stop` is empty)
+//
+// returns the expressions matching each symbol of `r`, the range of `elems` matching `r`, and
+// whether the match is ambiguous--i.e., the last hole of `r` could end before the keyword of a rule
+// that starts with a hole
+func (x *expander) match(r mixfix, elems []expr, i int, stop string) (args [][]expr, start, end int, ambiguous, ok bool) {
+	args = make([][]expr, len(r.symbols))
+	if r.keyword > 0 {
+		if !distribute(elems[:i], args[:r.keyword]) {
+			return nil, 0, 0, false, false
+		}
+	} else {
+		start = i
+	}
+
+	end = i + 1
+	for j := r.keyword + 1; j < len(r.symbols); {
+		holes := j
+		for j < len(r.symbols) && r.symbols[j].IsLeft() {
+			j++
+		}
+
+		if j == len(r.symbols) {
+			var next int
+			next, ambiguous = x.scan(elems, end, stop)
+			if !distribute(elems[end:next], args[holes:j]) {
+				return nil, 0, 0, false, false
+			}
+			end = next
+			break
+		}
+
+		next, _ := x.scan(elems, end, keywordString(r.symbols[j]))
+		if next == len(elems) || !distribute(elems[end:next], args[holes:j]) {
+			return nil, 0, 0, false, false
+		}
+		end = next + 1
+		j++
+	}
+	return args, start, end, ambiguous, true
+}
+
+// returns the index of the first name `stop` in `elems` at or after `i` that isn't part of a nested
+// use of a rule (or the length of `elems` if there isn't one); `open` is true iff a keyword of a rule
+// that starts with a hole is found along the way
+func (x *expander) scan(elems []expr, i int, stop string) (end int, open bool) {
+	for ; i < len(elems); i++ {
+		if n, isName := elems[i].(name); isName && stop != "" && nameString(n) == stop {
+			return i, open
+		}
+		r, found := x.rule(elems[i])
+		if !found {
+			continue
+		} else if r.keyword > 0 {
+			open = true
+		} else if _, _, end, _, ok := x.match(r, elems, i, stop); ok {
+			i = end - 1
+		}
+	}
+	return len(elems), open
+}
+
+// splits `elems` between the holes `args`: each hole but the last is matched by one expression, and
+// the last is matched by the rest
+func distribute(elems []expr, args [][]expr) bool {
+	if len(args) == 0 || len(elems) < len(args) {
+		return len(elems) == len(args)
+	}
+	for i := range args[:len(args)-1] {
+		args[i] = elems[i : i+1]
+	}
+	args[len(args)-1] = elems[len(args)-1:]
+	return true
+}
+
+// substitutes the expressions matching the holes of `r` into the right-hand side of `r`
+func (x *expander) substitute(r mixfix, args [][]expr, use api.Position) expr {
+	s := substitution{
+		expander: x,
+		mixfix:   r,
+		g:        generator{at: use},
+		holes:    make(map[string]expr),
+		binders:  make(map[string]name),
+		renames:  make(map[string]string),
+	}
+	for i, symbol := range r.symbols {
+		id, _, isKeyword := symbol.Break()
+		if isKeyword {
+			continue
+		}
+
+		hole := nameString(identAsName(id.id))
+		if !id.binding {
+			s.holes[hole] = x.spine(args[i], api.WeakenRangeOver(args[i][0], args[i][len(args[i])-1]))
+			continue
+		}
+
+		n, isName := args[i][0].(name)
+		if len(args[i]) != 1 || !isName || !isLowerName(n) {
+			x.errorAt(ExpectedSyntaxBinder+": `{"+hole+"}`", api.WeakenRangeOver(args[i][0], args[i][len(args[i])-1]))
+			n = s.g.name(hole)
+		}
+		s.binders[hole] = n
+	}
+	return s.expr(r.rule.Snd())
+}
+
+// the right-hand side of a rule, rewritten for a use of the rule
+//
+// expansions are hygienic:
+//   - the variables a rule binds are renamed to fresh names, so they can't capture the names in the
+//     expressions matching the rule's holes; the exception is a binder slot (e.g., `{x}`), which
+//     binds the name matching it
+//   - the rule's other names refer to the names in scope where the rule is defined, so it's an error
+//     for one of them to be bound locally where the rule is used
+//
+// every token of the right-hand side is placed at the use, so errors in an expansion point at the
+// use
+type substitution struct {
+	*expander
+	mixfix mixfix
+	g      generator
+	// expressions matching each hole
+	holes map[string]expr
+	// names matching each binder slot
+	binders map[string]name
+	// fresh names of the variables bound by the right-hand side
+	renames map[string]string
+}
+
+// returns a copy of `s` whose renames can be extended without affecting `s`
+func (s substitution) scope() substitution {
+	renames := make(map[string]string, len(s.renames))
+	for k, v := range s.renames {
+		renames[k] = v
+	}
+	s.renames = renames
+	return s
+}
+
+func (g generator) relocate(tok api.Token) api.Token {
+	if t, isToken := tok.(token.Token); isToken {
+		t.Start, t.End = g.at.Pos()
+		return t
+	}
+	return tok
+}
+
+func (s substitution) expr(e expr) expr {
+	switch y := e.(type) {
+	case name:
+		return s.name(y)
+	case literal:
+		return data.EOne[literal](s.g.relocate(y.Extract()))
+	case exprApp:
+		args := y.Snd().Elements()
+		for i, arg := range args {
+			args[i] = s.expr(arg)
+		}
+		return s.g.app(s.expr(y.Fst()), args...)
+	case lambdaAbstraction:
+		inner := s.scope()
+		binders := data.MapNonEmpty(inner.lambdaBinder)(y.Fst().NonEmpty)
+		return data.EMakePair[lambdaAbstraction](lambdaBinders{binders}, inner.expr(y.Snd()))
+	case letExpr:
+		inner := s.scope()
+		group := data.MapNonEmpty(inner.bindMember)(y.Fst().NonEmpty)
+		group = data.MapNonEmpty(inner.bindingGroupMember)(group)
+		return data.EMakePair[letExpr](letBinding{group}, inner.expr(y.Snd()))
+	case caseExpr:
+		var lifted []bindingGroupMember
+		scrutinee := s.scrutinee(y.Fst(), &lifted)
+		arms := data.MapNonEmpty(func(arm caseArm) caseArm {
+			inner := s.scope()
+			pat := inner.bindPattern(arm.Fst())
+			return data.EMakePair[caseArm](pat, inner.defBody(arm.Snd()))
+		})(y.Snd().NonEmpty)
+		c := data.EMakePair[caseExpr](scrutinee, caseArms{arms})
+		if len(lifted) == 0 {
+			return c
+		}
+		return data.EMakePair[letExpr, letBinding, expr](letBinding{data.Construct(lifted[0], lifted[1:]...)}, c)
+	}
+	return e
+}
+
+func (s substitution) name(n name) expr {
+	x := nameString(n)
+	if renamed, found := s.renames[x]; found {
+		return s.g.name(renamed)
+	} else if b, found := s.binders[x]; found {
+		return b
+	} else if e, found := s.holes[x]; found {
+		return e
+	}
+
+	if s.local(x) {
+		s.errorWithNote(CapturedSyntaxName+": "+x, s.g.at, "syntax rule is defined here", s.mixfix.syntax)
+	}
+	return data.EOne[name](s.g.relocate(n.Extract()))
+}
+
+// binds a variable of the right-hand side, returning the name it's renamed to
+func (s substitution) bind(n name) name {
+	x := nameString(n)
+	if b, found := s.binders[x]; found {
+		delete(s.renames, x)
+		return b
+	}
+	s.fresh++
+	s.renames[x] = x + "'" + strconv.Itoa(s.fresh)
+	return s.g.name(s.renames[x])
+}
+
+func (s substitution) bindPattern(pat pattern) pattern {
+	switch p := pat.(type) {
+	case name:
+		if isLowerName(p) {
+			return s.bind(p)
+		}
+		return data.EOne[name](s.g.relocate(p.Extract()))
+	case literal:
+		return data.EOne[literal](s.g.relocate(p.Extract()))
+	case patternApp:
+		args := p.Snd().Elements()
+		for i, arg := range args {
+			args[i] = s.bindPattern(arg)
+		}
+		return s.g.patternApp(s.bindPattern(p.Fst()), args...)
+	case patternEnclosed:
+		return patternEnclosed{implicit: p.implicit, NonEmpty: data.MapNonEmpty(s.bindPattern)(p.NonEmpty)}
+	}
+	return pat
+}
+
+func (s substitution) bindBinder(b binder) binder {
+	id, pat, isPattern := b.Break()
+	if isPattern {
+		return data.Inr[ident](s.bindPattern(pat))
+	}
+	lower, _, isUpper := id.Break()
+	if isUpper {
+		return b
+	}
+	return data.Inl[pattern](data.Inl[upperIdent](lowerIdent(s.bind(name(lower)))))
+}
+
+func (s substitution) lambdaBinder(b lambdaBinder) lambdaBinder {
+	if bound, _, isWildcard := b.Either.Break(); !isWildcard {
+		return lambdaBinder{data.Inl[wildcard](s.bindBinder(bound))}
+	}
+	return b
+}
+
+// binds the name a member of a let binding group binds
+func (s substitution) bindMember(member bindingGroupMember) bindingGroupMember {
+	bound, ty, isTyping := member.Break()
+	if !isTyping {
+		return data.Inl[data.Pair[typing, data.Maybe[expr]]](data.MakePair(s.bindBinder(bound.Fst()), bound.Snd()))
+	}
+	t := ty.Fst()
+	t.typing = data.MakePair(s.bind(t.typing.Fst()), t.typing.Snd())
+	return data.Inr[data.Pair[binder, expr]](data.MakePair(t, ty.Snd()))
+}
+
+func (s substitution) bindingGroupMember(member bindingGroupMember) bindingGroupMember {
+	bound, ty, isTyping := member.Break()
+	if !isTyping {
+		return data.Inl[data.Pair[typing, data.Maybe[expr]]](data.MakePair(bound.Fst(), s.expr(bound.Snd())))
+	}
+	return data.Inr[data.Pair[binder, expr]](data.MakePair(ty.Fst(), data.MaybeMap(s.expr)(ty.Snd())))
+}
+
+// rewrites the scrutinee of a case expression; a hole matched by an expression that isn't a
+// pattern is bound by a let expression wrapping the case expression, appended to `lifted`
+func (s substitution) scrutinee(pat pattern, lifted *[]bindingGroupMember) pattern {
+	switch p := pat.(type) {
+	case name:
+		switch e := s.name(p).(type) {
+		case name:
+			return e
+		case literal:
+			return e
+		default:
+			s.fresh++
+			v := s.g.name(nameString(p) + "'" + strconv.Itoa(s.fresh))
+			*lifted = append(*lifted, data.Inl[data.Pair[typing, data.Maybe[expr]]](data.MakePair(data.Inl[pattern](data.Inl[upperIdent](lowerIdent(v))), e)))
+			return v
+		}
+	case literal:
+		return data.EOne[literal](s.g.relocate(p.Extract()))
+	case patternApp:
+		args := p.Snd().Elements()
+		for i, arg := range args {
+			args[i] = s.scrutinee(arg, lifted)
+		}
+		return s.g.patternApp(s.scrutinee(p.Fst(), lifted), args...)
+	case patternEnclosed:
+		elems := p.Elements()
+		for i, elem := range elems {
+			elems[i] = s.scrutinee(elem, lifted)
+		}
+		return patternEnclosed{implicit: p.implicit, NonEmpty: data.Construct(elems[0], elems[1:]...)}
+	}
+	return pat
+}
+
+// rewrites the body of a case arm; bodies with with clauses or where clauses are left as is
+func (s substitution) defBody(body defBody) defBody {
+	_, possible, isPossible := body.Break()
+	if !isPossible {
+		return body
+	}
+	_, e, isExpr := possible.Fst().Break()
+	if _, hasWhere := possible.Snd().Break(); !isExpr || hasWhere {
+		return body
+	}
+	return data.EInr[defBody](data.EMakePair[defBodyPossible](data.Inr[withClause](s.expr(e)), possible.Snd()))
+}
@@ -0,0 +1,43 @@
+//go:build test
+// +build test
+
+package parser
+
+import "testing"
+
+const syntaxPrelude = `ifThenElse : Bool -> a -> a -> a
+ifThenElse True t _ = t
+ifThenElse False _ e = e
+
+syntax ` + "`if` c `then` t `else` e" + ` = ifThenElse c t e
+
+syntax ` + "`fun` {x} `to` body" + ` = \x => body
+
+syntax ` + "x `or` y" + ` = ifThenElse x True y
+
+`
+
+func TestExpandSyntax(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []report
+	}{
+		{"use", "f : Bool -> Bool\nf b = if b then False else True", nil},
+		{"nested", "f : Bool -> Bool -> Bool\nf a b = if if a then b else a then b else if b then a else b", nil},
+		{"argument", "f : Bool -> Nat\nf b = Succ if b then Zero else Zero", nil},
+		{"leading hole", "f : Bool -> Bool\nf b = b or False", nil},
+		{"binder", "f : Bool -> Bool\nf = fun y to y", nil},
+		{"mismatch", "f : Bool -> Bool\nf b = if b then False", []report{syntaxError(SyntaxMismatch + ": `if`")}},
+		{"binder mismatch", "f : Bool -> Bool\nf = fun True to True", []report{syntaxError(ExpectedSyntaxBinder + ": `{x}`")}},
+		{"ambiguous", "f : Bool -> Bool\nf b = if b then False else b or True", []report{syntaxError(AmbiguousSyntax)}},
+		{"captured", "f : Bool -> Bool\nf ifThenElse = if ifThenElse then False else True", []report{syntaxError(CapturedSyntaxName + ": ifThenElse")}},
+		{"overlapping", "syntax `if` c `do` t = ifThenElse c t t", []report{syntaxError(OverlappingSyntax + ": `if`")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectReports(t, checkSource(t, typeCheckPrelude+syntaxPrelude+test.source), test.expected...)
+		})
+	}
+}
@@ -0,0 +1,1128 @@
+// =================================================================================================
+// type checking: bidirectional checking of typings, type definitions, and definitions
+// =================================================================================================
+
+package parser
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/api/token"
+	"github.com/petersalex27/yew/common/data"
+	"github.com/petersalex27/yew/internal/core"
+	"github.com/petersalex27/yew/internal/errors"
+	"github.com/petersalex27/yew/internal/symbol"
+)
+
+// the type of types
+var typeType = symbol.MakeConstant("", "Type")
+
+type typeChecker struct {
+	reporter
+	// types of all symbols in scope
+	types *symbol.Table
+	// type aliases, mapped to the type they alias
+	aliases map[string]typ
+	// aliases currently being expanded, used to stop cyclic aliases from expanding forever
+	expanding map[string]bool
+	// solves the free variables of types
+	unifier *symbol.Unifier
+	// typings of declared names, used to point at the source of an expected type
+	typings map[string]typ
+	// source of the type currently expected, nil if unknown
+	origin api.Positioned
+	// positions of arguments passed for erased binders
+	erased map[[2]int]bool
+	// free variables standing in for types the checker cannot determine
+	unknowns map[string]bool
+	// implicit arguments inserted at applications, solved once their definition has been checked
+	implicits []implicitArg
+	// types being inferred for definitions without a typing; the free variables they're left with
+	// are generalized rather than reported as unsolved
+	inferring []api.Type
+	// specs defined in the source
+	specs map[string]specInfo
+	// instances of each spec
+	instances map[string][]instanceInfo
+	// constraints required by applications, resolved once their definition has been checked
+	wanted []wantedConstraint
+	// constraints assumed to hold, e.g., `Eq a` while checking a definition of `Eq a => a -> Bool`
+	givens []api.Type
+	// constructors defined in the source
+	constructors map[string]core.Constructor
+	// constructors of each defined type, in the order they're defined
+	signatures map[string][]string
+}
+
+// an implicit argument inserted at an application
+type implicitArg struct {
+	// free variable standing in for the argument
+	arg api.Type
+	// implicit binder the argument is passed for
+	binder symbol.Pi
+	// application the argument was inserted into
+	at api.Positioned
+}
+
+// sets the source of expected types to `n`, returning a function that restores the previous source
+func (c *typeChecker) expectingFrom(n api.Positioned) (restore func()) {
+	origin := c.origin
+	c.origin = n
+	return func() { c.origin = origin }
+}
+
+// returns the source of the type of `x` if `x` has a typing, otherwise returns `fallback`
+func (c *typeChecker) originOf(x string, fallback api.Positioned) api.Positioned {
+	if ty, found := c.typings[x]; found {
+		return ty
+	}
+	return fallback
+}
+
+// type checks the AST of `ps`, returning the checker so the types it found can be queried
+func checkTypes(ps *ParserState) *typeChecker {
+	c := &typeChecker{
+		reporter:     reporter{ps, errors.Type},
+		types:        symbol.New(),
+		aliases:      make(map[string]typ),
+		expanding:    make(map[string]bool),
+		unifier:      symbol.NewUnifier(),
+		typings:      make(map[string]typ),
+		erased:       make(map[[2]int]bool),
+		unknowns:     make(map[string]bool),
+		specs:        make(map[string]specInfo),
+		instances:    make(map[string][]instanceInfo),
+		constructors: make(map[string]core.Constructor),
+		signatures:   make(map[string][]string),
+	}
+	c.types.DeclareTyped(typeType.Constant(), typeType)
+
+	if b, just := ps.ast.body.Break(); just {
+		c.group(bodyElements(b), true)
+	}
+	return c
+}
+
+// declares the types of each name of a group, then checks each element
+//
+// definitions without a typing are checked first, in the order they're defined, each against a
+// fresh type that's then declared as the type found for it--generalized if `generalize` is true--so
+// the elements checked after it can use it
+func (c *typeChecker) group(elems []mainElement, generalize bool) {
+	for _, elem := range elems {
+		if alias, isAlias := elem.(typeAlias); isAlias {
+			c.aliases[nameString(alias.alias.Fst())] = alias.alias.Snd()
+		}
+	}
+
+	for _, elem := range elems {
+		c.declareElement(elem)
+	}
+
+	c.types.Enter() // fresh types of the untyped definitions
+	names, types := c.declareUntyped(elems)
+	c.types.Enter() // types found for the untyped definitions checked so far
+	found := make([]api.Type, len(names))
+	untyped := make(map[string]bool, len(names))
+	for i, x := range names {
+		untyped[x] = true
+		c.inferring = append(c.inferring, types[i])
+		for _, elem := range elems {
+			if d, isDef := elem.(def); isDef && definedNameString(d) == x {
+				c.def(d)
+			}
+		}
+		c.inferring = c.inferring[:len(c.inferring)-1]
+
+		if found[i] = c.unifier.Apply(types[i]); generalize {
+			found[i] = c.generalizeInferred(found[i])
+		}
+		c.types.DeclareTyped(x, found[i])
+	}
+
+	for _, elem := range elems {
+		if d, isDef := elem.(def); !isDef || !untyped[definedNameString(d)] {
+			c.mainElement(elem)
+		}
+	}
+	c.types.Exit()
+	c.types.Exit()
+
+	for i, x := range names {
+		c.types.DeclareTyped(x, found[i])
+	}
+	c.coverDefs(elems)
+}
+
+// returns the name `d` defines, or "" if it doesn't define one
+func definedNameString(d def) string {
+	if n, _, ok := definedName(d.pattern); ok {
+		return nameString(n)
+	}
+	return ""
+}
+
+// declares a fresh type for each name defined by `elems` without a typing, returning the names and
+// their types
+func (c *typeChecker) declareUntyped(elems []mainElement) (names []string, types []api.Type) {
+	typed := make(map[string]bool)
+	for _, elem := range elems {
+		if t, isTyping := elem.(typing); isTyping {
+			typed[nameString(t.typing.Fst())] = true
+		}
+	}
+
+	for _, elem := range elems {
+		d, isDef := elem.(def)
+		if !isDef {
+			continue
+		}
+		n, _, ok := definedName(d.pattern)
+		if x := nameString(n); ok && !typed[x] {
+			typed[x] = true
+			ty := c.types.Fresh()
+			c.types.DeclareTyped(x, ty)
+			names, types = append(names, x), append(types, ty)
+		}
+	}
+	return names, types
+}
+
+func (c *typeChecker) declare(n name, ty typ) {
+	c.typings[nameString(n)] = ty
+	c.types.DeclareTyped(nameString(n), c.generalize(c.elaborate(ty)))
+}
+
+// binds each type variable of `ty` that isn't otherwise bound with an implicit, erased binder, e.g.,
+// `a -> a` becomes `{a : _} -> a -> a`
+func (c *typeChecker) generalize(ty api.Type) api.Type {
+	var vars []string
+	for _, x := range symbol.FreeVariables(ty) {
+		if _, found := c.types.Lookup(x); !found { // otherwise, `x` is a term variable in scope
+			vars = append(vars, x)
+		}
+	}
+
+	for i := len(vars) - 1; i >= 0; i-- {
+		ty = symbol.MakePi(vars[i], symbol.Erase, true, c.types.Fresh(), ty)
+	}
+	return ty
+}
+
+// names each free variable the type inferred for a definition is left with, then generalizes it,
+// e.g., `~a -> ~a` becomes `{a : _} -> a -> a`
+func (c *typeChecker) generalizeInferred(ty api.Type) api.Type {
+	next := 0
+	for _, x := range symbol.Frees(ty) {
+		if !c.unsolved(symbol.MakeVariable(x)) {
+			continue
+		}
+		var name string
+		for name = typeVariableName(next); c.inScope(name, ty); name = typeVariableName(next) {
+			next++
+		}
+		ty = symbol.Substitute(ty, x, symbol.MakeVariable(name))
+	}
+	return c.generalize(ty)
+}
+
+// returns the `i`-th name of a type variable: `a`, `b`, ..., `z`, `a'`, `b'`, ...
+func typeVariableName(i int) string {
+	return string(rune('a'+i%26)) + strings.Repeat("'", i/26)
+}
+
+// returns true iff `x` is declared or is a variable of `ty`
+func (c *typeChecker) inScope(x string, ty api.Type) bool {
+	if _, found := c.types.Lookup(x); found {
+		return true
+	}
+	return slices.Contains(symbol.FreeVariables(ty), x)
+}
+
+func (c *typeChecker) declareElement(elem mainElement) {
+	switch e := elem.(type) {
+	case typing:
+		c.declare(e.typing.Fst(), e.typing.Snd())
+	case typeDef:
+		c.declare(e.typedef.Fst().typing.Fst(), e.typedef.Fst().typing.Snd())
+		c.declareConstructors(e)
+		if constructors, _, isImpossible := e.typedef.Snd().Break(); !isImpossible {
+			for _, constructor := range constructors.Elements() {
+				c.declare(constructor.constructor.Fst(), constructor.constructor.Snd())
+			}
+		}
+	case typeAlias:
+		c.types.DeclareTyped(nameString(e.alias.Fst()), c.unknown())
+	case specDef:
+		c.declareSpec(e)
+	case specInst:
+		c.declareInstance(e)
+	}
+}
+
+func (c *typeChecker) mainElement(elem mainElement) {
+	switch e := elem.(type) {
+	case def:
+		c.def(e)
+	case typing:
+		c.isType(e.typing.Snd())
+	case typeDef:
+		c.typeDef(e)
+	case typeAlias:
+		c.typeOf(e.alias.Snd())
+	case specDef:
+		c.types.Enter()
+		for _, param := range c.specs[nameString(name(e.specHead.Snd().Fst()))].params {
+			if param != "" {
+				c.types.DeclareTyped(param, c.unknown())
+			}
+		}
+		for _, member := range e.specBody.Elements() {
+			if _, ty, isTyping := member.Break(); isTyping {
+				c.isType(ty.typing.Snd())
+			}
+		}
+		c.types.Exit()
+	case specInst:
+		c.instanceMembers(e)
+	}
+}
+
+// checks that the typing of a type definition is a type and that each of its constructors
+// constructs a member of that type
+func (c *typeChecker) typeDef(td typeDef) {
+	ty := td.typedef.Fst()
+	c.isType(ty.typing.Snd())
+
+	constructors, _, isImpossible := td.typedef.Snd().Break()
+	if isImpossible {
+		return
+	}
+
+	defined := nameString(ty.typing.Fst())
+	for _, constructor := range constructors.Elements() {
+		conTy := constructor.constructor.Snd()
+		c.isType(conTy)
+		if head := resultHead(c.elaborate(conTy)); head != "" && head != defined {
+			c.errorAt(IllegalConstructorResult+": "+defined, conTy)
+		}
+	}
+}
+
+// returns the head of the final target of a (possibly) function type, or "" if it isn't a constant
+func resultHead(ty api.Type) string {
+	for {
+		pi, isPi := ty.(symbol.Pi)
+		if !isPi {
+			break
+		}
+		ty = pi.Target()
+	}
+	if _, isVar := symbol.IsVariable(ty); isVar {
+		return ""
+	}
+	head, _ := ty.Break()
+	return head
+}
+
+// =================================================================================================
+// elaboration: syntactic types to api.Type values
+// =================================================================================================
+
+func multiplicityOf(mode data.Maybe[modality]) int8 {
+	m, just := mode.Break()
+	if !just {
+		return symbol.Unlimited
+	} else if token.Erase.Match(m.Extract()) {
+		return symbol.Erase
+	}
+	return symbol.Once
+}
+
+// elaborates a syntactic type into an api.Type
+func (c *typeChecker) elaborate(ty typ) api.Type {
+	switch x := ty.(type) {
+	case name:
+		return c.elaborateName(x)
+	case appType:
+		return c.elaborateApp(x)
+	case functionType:
+		return c.elaborateFunction(x.Fst(), c.elaborate(x.Snd()))
+	case forallType:
+		target := c.elaborate(x.Snd())
+		binders := x.Fst().Elements()
+		for i := len(binders) - 1; i >= 0; i-- {
+			target = symbol.MakePi(nameString(identAsName(binders[i])), symbol.Erase, true, typeType, target)
+		}
+		return target
+	case enclosedType:
+		return c.elaborate(x.typ)
+	case innerTyping:
+		return c.elaborate(x.typing.Snd())
+	case implicitTyping:
+		return c.elaborate(x.Fst())
+	case constrainedType:
+		target := c.elaborate(x.Snd())
+		constraints := c.elaborateConstraint(x.Fst())
+		for i := len(constraints) - 1; i >= 0; i-- {
+			target = symbol.MakeConstraint(constraints[i], target)
+		}
+		return target
+	case unitType:
+		return symbol.MakeConstant("", "()")
+	case literal:
+		return symbol.MakeConstant("", x.Extract().String())
+	}
+	return c.unknown()
+}
+
+func (c *typeChecker) elaborateName(n name) api.Type {
+	x := nameString(n)
+	if alias, isAlias := c.aliases[x]; isAlias && !c.expanding[x] {
+		c.expanding[x] = true
+		defer delete(c.expanding, x)
+		return c.elaborate(alias)
+	} else if isLowerName(n) {
+		return symbol.MakeVariable(x)
+	}
+	return symbol.MakeConstant("", x)
+}
+
+func (c *typeChecker) elaborateApp(app appType) api.Type {
+	head, args := app.Fst(), app.Snd().Elements()
+	var out api.App
+	if qualifier, member, ok := qualifiedName(head, args[0]); ok {
+		out = api.App{symbol.MakeConstant(qualifier, member)}
+		args = args[1:]
+	} else if elaborated := c.elaborate(head); isApp(elaborated) {
+		out = append(out, elaborated.(api.App)...)
+	} else {
+		out = api.App{elaborated}
+	}
+
+	for _, arg := range args {
+		out = append(out, c.elaborate(arg))
+	}
+
+	if len(out) == 1 {
+		return out[0]
+	}
+	return out
+}
+
+func isApp(ty api.Type) bool {
+	_, ok := ty.(api.App)
+	return ok
+}
+
+// returns the qualifier and member of a qualified name, e.g., `bool` and `Bool` for `bool.Bool`
+func qualifiedName(head, next api.Node) (qualifier, member string, ok bool) {
+	n, isName := head.(name)
+	a, isAccess := next.(access)
+	if !isName || !isAccess {
+		return "", "", false
+	}
+	return nameString(n), a.Extract().String(), true
+}
+
+// returns the default expression of `ty` if `ty` binds names with one, e.g., `a` in `{x : A := a}`
+func defaultOf(ty typ) (e expr, ok bool) {
+	enclosed, isEnclosed := ty.(enclosedType)
+	if !isEnclosed {
+		return e, false
+	}
+	it, isImplicit := enclosed.typ.(implicitTyping)
+	if !isImplicit {
+		return e, false
+	}
+	return it.Snd().Extract(), true
+}
+
+// returns the typing enclosed by `ty` if `ty` binds names, e.g., `(x : A)` and `{x : A := a}`
+func enclosedTyping(ty typ) (it innerTyping, implicit bool, ok bool) {
+	enclosed, isEnclosed := ty.(enclosedType)
+	if !isEnclosed {
+		return it, false, false
+	}
+
+	switch x := enclosed.typ.(type) {
+	case innerTyping:
+		return x, enclosed.implicit, true
+	case implicitTyping:
+		return x.Fst(), enclosed.implicit, true
+	}
+	return it, false, false
+}
+
+func (c *typeChecker) elaborateFunction(lhs typ, target api.Type) api.Type {
+	it, implicit, ok := enclosedTyping(lhs)
+	if !ok {
+		return symbol.MakePi("", symbol.Unlimited, false, c.elaborate(lhs), target)
+	}
+
+	domain := c.elaborate(it.typing.Snd())
+	mult := multiplicityOf(it.mode)
+	defaultExpr, hasDefault := defaultOf(lhs)
+	terms := it.typing.Fst().Elements()
+	for i := len(terms) - 1; i >= 0; i-- {
+		binder := ""
+		if n, isName := terms[i].(name); isName {
+			binder = nameString(n)
+		}
+		pi := symbol.MakePi(binder, mult, implicit, domain, target)
+		if hasDefault {
+			pi = pi.WithDefault(c.exprAsType(defaultExpr))
+		}
+		target = pi
+	}
+	return target
+}
+
+// =================================================================================================
+// checking and synthesis
+// =================================================================================================
+
+// reports a type mismatch at `at` when `expected` and `actual` do not unify
+//
+// if the source of the expected type is known, the report also points to it
+func (c *typeChecker) expect(expected, actual api.Type, at api.Positioned) {
+	err := c.unifier.Unify(expected, actual)
+	if err == nil {
+		return
+	}
+
+	msg := TypeMismatch + ": expected `" + symbol.String(c.unifier.Apply(expected)) + "`, got `" + symbol.String(c.unifier.Apply(actual)) + "`"
+	if mismatch, ok := err.(symbol.Mismatch); ok && mismatch.Infinite {
+		msg = InfiniteType + ": `" + symbol.String(mismatch.Expected) + "` ~ `" + symbol.String(mismatch.Actual) + "`"
+	}
+
+	if c.origin == nil {
+		c.errorAt(msg, at)
+	} else {
+		c.errorWithNote(msg, at, "expected type comes from here", c.origin)
+	}
+}
+
+// checks that `ty` is a type
+func (c *typeChecker) isType(ty typ) {
+	c.expect(typeType, c.typeOf(ty), ty)
+}
+
+func (c *typeChecker) lookup(x string) api.Type {
+	if ty, found := c.types.Lookup(x); found {
+		return ty
+	}
+	return c.unknown() // unknown, e.g., imported symbols
+}
+
+// synthesizes the type of a syntactic type
+func (c *typeChecker) typeOf(ty typ) api.Type {
+	switch x := ty.(type) {
+	case name:
+		if alias, isAlias := c.aliases[nameString(x)]; isAlias && !c.expanding[nameString(x)] {
+			c.expanding[nameString(x)] = true
+			defer delete(c.expanding, nameString(x))
+			return c.typeOf(alias)
+		}
+		return c.lookup(nameString(x))
+	case appType:
+		head, args := x.Fst(), x.Snd().Elements()
+		if _, _, ok := qualifiedName(head, args[0]); ok {
+			return c.unknown()
+		}
+		fnTy := c.typeOf(head)
+		for _, arg := range args {
+			var ok bool
+			if fnTy, ok = c.applyType(fnTy, arg); !ok {
+				c.errorAt(IllegalApplication+": `"+symbol.String(fnTy)+"`", x)
+				return c.unknown()
+			}
+		}
+		return fnTy
+	case functionType:
+		c.types.Enter()
+		defer c.types.Exit()
+		if it, _, ok := enclosedTyping(x.Fst()); ok {
+			c.isType(it.typing.Snd())
+			domain := c.elaborate(it.typing.Snd())
+			if e, hasDefault := defaultOf(x.Fst()); hasDefault {
+				c.check(e, domain)
+			}
+			for _, term := range it.typing.Fst().Elements() {
+				if n, isName := term.(name); isName {
+					c.types.DeclareTyped(nameString(n), domain)
+				}
+			}
+		} else {
+			c.isType(x.Fst())
+		}
+		c.isType(x.Snd())
+		return typeType
+	case forallType:
+		c.types.Enter()
+		defer c.types.Exit()
+		for _, id := range x.Fst().Elements() {
+			c.types.DeclareTyped(nameString(identAsName(id)), typeType)
+		}
+		c.isType(x.Snd())
+		return typeType
+	case enclosedType:
+		return c.typeOf(x.typ)
+	case innerTyping:
+		return c.typeOf(x.typing.Snd())
+	case implicitTyping:
+		return c.typeOf(x.Fst())
+	case constrainedType:
+		return c.typeOf(x.Snd())
+	case unitType:
+		return typeType
+	case literal:
+		return literalType(x)
+	}
+	return c.unknown()
+}
+
+// applies a type of type `fnTy` to the type-level argument `arg`, returning the type of the
+// application and whether the application is legal
+func (c *typeChecker) applyType(fnTy api.Type, arg typ) (api.Type, bool) {
+	fnTy = c.skipImplicits(fnTy)
+	if _, isVar := symbol.IsVariable(fnTy); isVar {
+		return c.unknown(), true
+	}
+
+	pi, isPi := fnTy.(symbol.Pi)
+	if !isPi {
+		return fnTy, false
+	}
+	c.expect(pi.Domain(), c.typeOf(arg), arg)
+	return c.instantiate(pi, c.elaborate(arg)), true
+}
+
+// returns the target of `pi` with its binder replaced by `arg`
+func (c *typeChecker) instantiate(pi symbol.Pi, arg api.Type) api.Type {
+	if pi.Binder() == "" {
+		return pi.Target()
+	}
+	return symbol.Substitute(pi.Target(), pi.Binder(), arg)
+}
+
+// instantiates each leading implicit binder of `ty` with a fresh type
+func (c *typeChecker) skipImplicits(ty api.Type) api.Type {
+	for {
+		ty = c.unifier.Apply(ty)
+		pi, isPi := ty.(symbol.Pi)
+		if !isPi || !pi.Implicit() {
+			return ty
+		}
+		ty = c.instantiate(pi, c.types.Fresh())
+	}
+}
+
+// inserts an implicit argument into the application `at` for each leading implicit binder of `ty`,
+// where `from` is the source of `ty` (or nil)
+//
+// the arguments are solved by unification (see `solveImplicits`), and the constraints by instance
+// resolution (see `solveConstraints`)
+func (c *typeChecker) insertImplicits(ty api.Type, at, from api.Positioned) api.Type {
+	for {
+		ty = c.unifier.Apply(ty)
+		pi, isPi := ty.(symbol.Pi)
+		if !isPi || !pi.Implicit() {
+			return ty
+		} else if pi.Constraint() {
+			c.wanted = append(c.wanted, wantedConstraint{constraint: pi.Domain(), at: at, from: from})
+			ty = pi.Target()
+			continue
+		}
+		arg := c.types.Fresh()
+		c.implicits = append(c.implicits, implicitArg{arg: arg, binder: pi, at: at})
+		ty = c.instantiate(pi, arg)
+	}
+}
+
+// returns a fresh type for a term whose type cannot be determined, e.g., an imported symbol
+//
+// implicit arguments solved by these types are never reported as unsolved
+func (c *typeChecker) unknown() api.Type {
+	ty := c.types.Fresh()
+	x, _ := symbol.IsVariable(ty)
+	c.unknowns[x] = true
+	return ty
+}
+
+// solves each implicit argument inserted since the `mark`-th one
+//
+// arguments not determined by unification are passed the default argument of their binder; if the
+// binder has no default argument, the argument is reported as unsolved
+func (c *typeChecker) solveImplicits(mark int) {
+	pending := c.implicits[mark:]
+	c.implicits = c.implicits[:mark]
+
+	for _, implicit := range pending {
+		if !c.unsolved(implicit.arg) {
+			continue
+		} else if arg, ok := implicit.binder.Default(); ok {
+			restore := c.expectingFrom(nil)
+			c.expect(implicit.arg, arg, implicit.at)
+			restore()
+		}
+	}
+
+	// arguments solved by each other are only reported once
+	reported := make(map[string]bool)
+	for _, implicit := range pending {
+		if !c.unsolved(implicit.arg) {
+			continue
+		}
+		root, _ := symbol.IsVariable(c.unifier.Apply(implicit.arg))
+		if reported[root] || c.isInferring(root) {
+			continue
+		}
+		reported[root] = true
+
+		binding := implicit.binder.Binder()
+		if domain := c.unifier.Apply(implicit.binder.Domain()); !symbol.IsFree(domain) {
+			binding = binding + " : " + symbol.String(domain)
+		}
+		c.errorAt(UnsolvedImplicit+": `"+binding+"`", implicit.at)
+	}
+}
+
+// returns true iff the free variable `x` occurs in a type being inferred for a definition
+func (c *typeChecker) isInferring(x string) bool {
+	for _, ty := range c.inferring {
+		if slices.Contains(symbol.Frees(c.unifier.Apply(ty)), x) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns true iff `ty` is still an undetermined free variable that no unknown type depends on
+func (c *typeChecker) unsolved(ty api.Type) bool {
+	ty = c.unifier.Apply(ty)
+	x, _ := symbol.IsVariable(ty)
+	if !symbol.IsFree(ty) || c.unknowns[x] {
+		return false
+	}
+
+	// an unknown type may have been solved by `x`
+	for u := range c.unknowns {
+		if y, _ := symbol.IsVariable(c.unifier.Apply(symbol.MakeVariable(u))); x == y {
+			return false
+		}
+	}
+	return true
+}
+
+// removes each leading implicit binder of `ty`, leaving the variables it binds rigid and assuming
+// the constraints it binds hold
+func (c *typeChecker) skolemize(ty api.Type) api.Type {
+	for {
+		ty = c.unifier.Apply(ty)
+		pi, isPi := ty.(symbol.Pi)
+		if !isPi || !pi.Implicit() {
+			return ty
+		} else if pi.Constraint() {
+			c.addGiven(pi.Domain())
+		}
+		ty = pi.Target()
+	}
+}
+
+func literalType(lit literal) api.Type {
+	tok := lit.Extract()
+	switch {
+	case token.IntValue.Match(tok):
+		return symbol.MakeConstant("", "Int")
+	case token.FloatValue.Match(tok):
+		return symbol.MakeConstant("", "Float")
+	case token.CharValue.Match(tok):
+		return symbol.MakeConstant("", "Char")
+	}
+	return symbol.MakeConstant("", "String")
+}
+
+// checks a definition against its typing, or, if it has none, against the type being inferred for
+// it (see `group`)
+func (c *typeChecker) def(d def) {
+	n, _, ok := definedName(d.pattern)
+	if !ok {
+		return
+	}
+	if ty, found := c.types.Lookup(nameString(n)); found {
+		c.defAs(d, ty)
+	}
+}
+
+// checks a definition against `ty`
+func (c *typeChecker) defAs(d def, ty api.Type) {
+	n, params, ok := definedName(d.pattern)
+	if !ok {
+		return
+	}
+
+	c.types.Enter()
+	defer c.types.Exit()
+	defer c.expectingFrom(c.originOf(nameString(n), nil))()
+
+	givens, mark, wantedMark := c.givens, len(c.implicits), len(c.wanted)
+	defer func() { c.givens = givens }()
+
+	var rs []restricted
+	domains := make([]api.Type, len(params))
+	for i, param := range params {
+		if enclosed, isEnclosed := param.(patternEnclosed); !isEnclosed || !enclosed.implicit {
+			ty = c.skolemize(ty)
+		}
+
+		// the type of a definition without a typing is refined by its parameters
+		if c.unsolved(ty) {
+			enclosed, isEnclosed := param.(patternEnclosed)
+			fn := symbol.MakePi("", symbol.Unlimited, isEnclosed && enclosed.implicit, c.types.Fresh(), c.types.Fresh())
+			c.expect(ty, fn, param)
+			ty = c.unifier.Apply(ty)
+		}
+
+		if _, isVar := symbol.IsVariable(ty); isVar {
+			c.bindUnknown(param)
+			continue
+		}
+
+		pi, isPi := ty.(symbol.Pi)
+		if !isPi {
+			c.errorAt(TooManyParameters+": `"+symbol.String(ty)+"`", param)
+			return
+		}
+		c.checkPattern(param, pi.Domain())
+		rs = restrict(rs, param, pi.Multiplicity())
+		domains[i] = pi.Domain()
+		ty = c.instantiate(pi, c.patternAsType(param))
+	}
+
+	c.defBody(d.defBody, c.skolemize(ty), defLhs(n, params, domains))
+	c.solveImplicits(mark)
+	c.solveConstraints(wantedMark)
+	c.checkUsage(rs, func(x string) (usage, bool) { return c.defBodyUsage(x, d.defBody) })
+}
+
+// returns a type-level representation of a simple pattern, e.g., `x` or `Zero`
+func (c *typeChecker) patternAsType(pat pattern) api.Type {
+	switch p := pat.(type) {
+	case name:
+		if isLowerName(p) {
+			return symbol.MakeVariable(nameString(p))
+		}
+		return symbol.MakeConstant("", nameString(p))
+	case patternEnclosed:
+		if p.Len() == 1 {
+			return c.patternAsType(p.Head())
+		}
+	}
+	return c.unknown()
+}
+
+// checks a definition body against `expected`, where `lhs` is the left-hand side the body belongs to
+func (c *typeChecker) defBody(body defBody, expected api.Type, lhs refinable) {
+	_, possible, isPossible := body.Break()
+	if !isPossible {
+		return
+	}
+
+	c.types.Enter()
+	defer c.types.Exit()
+
+	if where, just := possible.Snd().Break(); just {
+		c.group(where.Elements(), false)
+	}
+
+	if w, e, isExpr := possible.Fst().Break(); isExpr {
+		c.check(e, expected)
+	} else {
+		c.withClause(w, expected, lhs)
+	}
+}
+
+// binds each variable of `pat` with an unknown type
+func (c *typeChecker) bindUnknown(pat pattern) {
+	switch p := pat.(type) {
+	case name:
+		if isLowerName(p) {
+			c.types.DeclareTyped(nameString(p), c.unknown())
+		}
+	case patternApp:
+		c.bindUnknown(p.Fst())
+		for _, arg := range p.Snd().Elements() {
+			c.bindUnknown(arg)
+		}
+	case patternEnclosed:
+		for _, elem := range p.Elements() {
+			c.bindUnknown(elem)
+		}
+	}
+}
+
+// returns true iff the application `args` contains an operator applied infix, e.g., `+` in `x + y`
+//
+// the operands of operators that weren't re-associated (see `resolveFixity`) are not checked
+func hasInfix[a api.Node](args []a) bool {
+	for _, arg := range args {
+		if _, isOperator := asOperator(arg); isOperator {
+			return true
+		}
+	}
+	return false
+}
+
+// checks `pat` against `expected`, binding each of its variables
+func (c *typeChecker) checkPattern(pat pattern, expected api.Type) {
+	switch p := pat.(type) {
+	case name:
+		if isLowerName(p) {
+			c.types.DeclareTyped(nameString(p), expected)
+		} else {
+			c.expect(expected, c.skipImplicits(c.lookup(nameString(p))), p)
+		}
+	case patternApp:
+		head, isName := p.Fst().(name)
+		args := p.Snd().Elements()
+		if !isName || isLowerName(head) || hasInfix(args) {
+			c.bindUnknown(p)
+			return
+		}
+
+		ty := c.lookup(nameString(head))
+		restore := c.expectingFrom(c.originOf(nameString(head), head))
+		for _, arg := range args {
+			ty = c.skipImplicits(ty)
+			if _, isVar := symbol.IsVariable(ty); isVar {
+				c.bindUnknown(arg)
+				continue
+			}
+			pi, isPi := ty.(symbol.Pi)
+			if !isPi {
+				c.errorAt(IllegalApplication+": `"+symbol.String(ty)+"`", p)
+				c.bindUnknown(arg)
+				continue
+			}
+			c.checkPattern(arg, pi.Domain())
+			ty = c.instantiate(pi, c.patternAsType(arg))
+		}
+		restore()
+		c.expect(expected, c.skipImplicits(ty), p)
+	case patternEnclosed:
+		if p.Len() != 1 {
+			c.bindUnknown(p)
+			return
+		}
+		c.checkPattern(p.Head(), expected)
+	case literal:
+		c.expect(expected, literalType(p), p)
+	}
+}
+
+// checks `e` against `expected`
+func (c *typeChecker) check(e expr, expected api.Type) {
+	switch x := e.(type) {
+	case lambdaAbstraction:
+		c.types.Enter()
+		defer c.types.Exit()
+		var rs []restricted
+		for _, binder := range x.Fst().Elements() {
+			expected = c.skipImplicits(expected)
+			b, _, isWildcard := binder.Either.Break()
+			if _, isVar := symbol.IsVariable(expected); isVar {
+				if !isWildcard {
+					c.bindBinderUnknown(b)
+				}
+				continue
+			}
+
+			pi, isPi := expected.(symbol.Pi)
+			if !isPi {
+				c.errorAt(TypeMismatch+": expected `"+symbol.String(expected)+"`, got a function", x)
+				return
+			}
+			if !isWildcard {
+				c.bindBinder(b, pi.Domain())
+				rs = restrictBinder(rs, b, pi.Multiplicity())
+			}
+			expected = c.instantiate(pi, c.unknown())
+		}
+		c.check(x.Snd(), expected)
+		c.checkUsage(rs, func(y string) (usage, bool) { return c.exprUsage(y, x.Snd()), true })
+	case letExpr:
+		c.types.Enter()
+		defer c.types.Exit()
+		c.letBinding(x.Fst())
+		c.check(x.Snd(), expected)
+	case caseExpr:
+		scrutinee := c.synthPattern(x.Fst())
+		for _, arm := range x.Snd().Elements() {
+			c.types.Enter()
+			restore := c.expectingFrom(x.Fst())
+			c.checkPattern(arm.Fst(), scrutinee)
+			restore()
+			c.defBody(arm.Snd(), expected, armLhs(arm.Fst(), scrutinee))
+			c.types.Exit()
+		}
+		c.coverArms(x, scrutinee)
+	default:
+		c.expect(expected, c.insertImplicits(c.synth(e), e, c.headOrigin(e)), e)
+	}
+}
+
+func (c *typeChecker) bindBinder(b binder, ty api.Type) {
+	id, pat, isPattern := b.Break()
+	if isPattern {
+		c.checkPattern(pat, ty)
+	} else {
+		c.types.DeclareTyped(nameString(identAsName(id)), ty)
+	}
+}
+
+func (c *typeChecker) bindBinderUnknown(b binder) {
+	c.bindBinder(b, c.unknown())
+}
+
+// synthesizes the type of a pattern in an expression position, e.g., a case scrutinee
+func (c *typeChecker) synthPattern(pat pattern) api.Type {
+	switch p := pat.(type) {
+	case name:
+		return c.lookup(nameString(p))
+	case literal:
+		return literalType(p)
+	case patternEnclosed:
+		if p.Len() == 1 {
+			return c.synthPattern(p.Head())
+		}
+	}
+	return c.unknown()
+}
+
+// synthesizes the type of `e`
+func (c *typeChecker) synth(e expr) api.Type {
+	switch x := e.(type) {
+	case name:
+		return c.lookup(nameString(x))
+	case literal:
+		return literalType(x)
+	case exprApp:
+		return c.synthApp(x)
+	case lambdaAbstraction:
+		c.types.Enter()
+		defer c.types.Exit()
+		domains := make([]api.Type, 0, x.Fst().Len())
+		for _, binder := range x.Fst().Elements() {
+			domain := c.unknown()
+			if b, _, isWildcard := binder.Either.Break(); !isWildcard {
+				c.bindBinder(b, domain)
+			}
+			domains = append(domains, domain)
+		}
+		ty := c.synth(x.Snd())
+		for i := len(domains) - 1; i >= 0; i-- {
+			ty = symbol.MakePi("", symbol.Unlimited, false, domains[i], ty)
+		}
+		return ty
+	case letExpr:
+		c.types.Enter()
+		defer c.types.Exit()
+		c.letBinding(x.Fst())
+		return c.synth(x.Snd())
+	case caseExpr:
+		ty := c.types.Fresh()
+		c.check(x, ty)
+		return ty
+	}
+	return c.unknown()
+}
+
+func (c *typeChecker) synthApp(app exprApp) api.Type {
+	head, args := app.Fst(), app.Snd().Elements()
+	if hasInfix(args) {
+		return c.unknown() // operands of infix names are not checked
+	} else if _, _, ok := qualifiedName(head, args[0]); ok {
+		return c.unknown()
+	}
+
+	fnTy := c.synth(head)
+	from := c.headOrigin(head)
+	defer c.expectingFrom(from)()
+	for _, arg := range args {
+		fnTy = c.insertImplicits(fnTy, app, from)
+		if c.unsolved(fnTy) { // e.g., the type of a parameter of a definition without a typing
+			c.expect(fnTy, symbol.MakePi("", symbol.Unlimited, false, c.types.Fresh(), c.types.Fresh()), app)
+			fnTy = c.unifier.Apply(fnTy)
+		} else if _, isVar := symbol.IsVariable(fnTy); isVar {
+			return c.unknown()
+		}
+
+		pi, isPi := fnTy.(symbol.Pi)
+		if !isPi {
+			c.errorAt(IllegalApplication+": `"+symbol.String(fnTy)+"`", app)
+			return c.unknown()
+		}
+		c.check(arg, pi.Domain())
+		if pi.Multiplicity() == symbol.Erase {
+			c.erasedArgument(arg)
+		}
+		fnTy = c.instantiate(pi, c.exprAsType(arg))
+	}
+	return fnTy
+}
+
+// returns the source of the type of an applied expression
+func (c *typeChecker) headOrigin(head expr) api.Positioned {
+	if n, isName := head.(name); isName {
+		return c.originOf(nameString(n), head)
+	}
+	return head
+}
+
+// returns a type-level representation of a simple expression, e.g., `x`, `Zero`, or `Succ n`
+func (c *typeChecker) exprAsType(e expr) api.Type {
+	switch x := e.(type) {
+	case name:
+		return c.patternAsType(x)
+	case literal:
+		return symbol.MakeConstant("", x.Extract().String())
+	case exprApp:
+		out := api.App{c.exprAsType(x.Fst())}
+		for _, arg := range x.Snd().Elements() {
+			out = append(out, c.exprAsType(arg))
+		}
+		return out
+	}
+	return c.unknown()
+}
+
+// declares each member of a let binding group, then checks each bound expression
+func (c *typeChecker) letBinding(group letBinding) {
+	members := group.Elements()
+	types := make([]api.Type, len(members))
+	for i, member := range members {
+		bound, ty, isTyping := member.Break()
+		if isTyping {
+			types[i] = c.elaborate(ty.Fst().typing.Snd())
+			c.typings[nameString(ty.Fst().typing.Fst())] = ty.Fst().typing.Snd()
+			c.types.DeclareTyped(nameString(ty.Fst().typing.Fst()), types[i])
+		} else {
+			types[i] = c.types.Fresh()
+			c.bindBinder(bound.Fst(), types[i])
+		}
+	}
+
+	for i, member := range members {
+		bound, ty, isTyping := member.Break()
+		if !isTyping {
+			c.check(bound.Snd(), types[i])
+		} else if e, just := ty.Snd().Break(); just {
+			restore := c.expectingFrom(ty.Fst().typing.Snd())
+			c.check(e, types[i])
+			restore()
+		}
+	}
+}
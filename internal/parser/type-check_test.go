@@ -0,0 +1,129 @@
+//go:build test
+// +build test
+
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/petersalex27/yew/api/util"
+	"github.com/petersalex27/yew/internal/lexer"
+)
+
+const typeCheckPrelude = `Nat : Type where (
+  Zero : Nat
+  Succ : Nat -> Nat
+)
+
+Bool : Type where (
+  True : Bool
+  False : Bool
+)
+
+List : Type -> Type where (
+  Nil : List a
+  Cons : a -> List a -> List a
+)
+
+`
+
+// an error or warning expected from checking a source
+type report struct {
+	// kind of the error, e.g., `Type` for a type error, or empty for a warning
+	kind string
+	// substring of the report's message
+	message string
+}
+
+func syntaxError(message string) report { return report{"Syntax", message} }
+func typeError(message string) report   { return report{"Type", message} }
+func warning(message string) report     { return report{"", message} }
+
+// parses `src` and runs the front end over it--expansion, deriving, name analysis, then type
+// checking--stopping after the first pass that fails; fails the test if `src` can't be parsed
+func checkSource(t *testing.T, src string) *ParserState {
+	t.Helper()
+	p := Init(lexer.Init(util.StringSource(src)))
+	if Run(p) == nil {
+		t.Fatalf("unexpected failure: %v", p.Errors())
+	}
+	if Expand(p) != nil && Derive(p) != nil && Analyze(p) {
+		Check(p)
+	}
+	return p.(*ParserState)
+}
+
+// fails the test unless the errors and warnings of `ps` are exactly `expected`, in order (errors
+// before warnings)
+func expectReports(t *testing.T, ps *ParserState, expected ...report) {
+	t.Helper()
+	actual := append(append([]error{}, ps.Errors()...), ps.Warnings()...)
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d report(s), got %d: %v", len(expected), len(actual), actual)
+	}
+	for i, e := range expected {
+		header, _, _ := strings.Cut(actual[i].Error(), "\n")
+		prefix := "Warning: "
+		if e.kind != "" {
+			prefix = "Error (" + e.kind + "): "
+		}
+		if !strings.Contains(header, prefix) || !strings.Contains(header, e.message) {
+			t.Errorf("expected %s%q, got %v", prefix, e.message, actual[i])
+		}
+	}
+}
+
+func TestCheckTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []report
+	}{
+		{"prelude", "", nil},
+		{"constant", "one : Nat\none = Succ Zero", nil},
+		{"argument mismatch", "two : Nat\ntwo = Succ True", []report{typeError(TypeMismatch + ": expected `Nat`, got `Bool`")}},
+		{"result mismatch", "three : Bool\nthree = Succ Zero", []report{typeError(TypeMismatch + ": expected `Bool`, got `Nat`")}},
+		{"params", "f : Nat -> Bool -> Nat\nf n b = n", nil},
+		{"too many params", "g : Nat -> Nat\ng n m = n", []report{typeError(TooManyParameters)}},
+		{"lambda", "k : Nat -> Nat\nk = \\x => Succ x", nil},
+		{"polymorphic constructor", "xs : List Nat\nxs = Cons Zero Nil", nil},
+		{"constructor patterns", "isZero : Nat -> Bool\nisZero Zero = True\nisZero (Succ n) = False", nil},
+		{"pattern mismatch", "isZero : Nat -> Bool\nisZero True = False", []report{typeError(TypeMismatch + ": expected `Nat`, got `Bool`"), warning(NonExhaustivePatterns)}},
+		{"literal", "lit : Nat\nlit = 1", []report{typeError(TypeMismatch + ": expected `Nat`, got `Int`")}},
+		{"not a type", "h : Zero -> Nat", []report{typeError(TypeMismatch + ": expected `Type`, got `Nat`")}},
+		{"bad constructor", "Bad : Type where (\n  Oops : Bool\n)", []report{typeError(IllegalConstructorResult)}},
+		{"let", "l : Nat\nl = let x : Nat := Zero in Succ x", nil},
+		{"let mismatch", "l : Nat\nl = let x : Bool := Zero in x", []report{typeError(TypeMismatch + ": expected `Bool`, got `Nat`"), typeError(TypeMismatch + ": expected `Nat`, got `Bool`")}},
+		{"where", "w : Nat\nw = y where (\n  y : Nat\n  y = Zero\n)", nil},
+		{"implicit binder", "i : {a : Type} -> a -> a\ni x = x", nil},
+		{"explicit binder", "e : (n : Nat) -> Nat\ne n = n", nil},
+		{"dependent binder", "Vec : Nat -> Type -> Type where (\n  VNil : Vec Zero a\n)\n\nv : {n : Nat} -> Vec n Nat -> Vec n Nat\nv xs = xs", nil},
+		{"alias", "alias N = Nat\n\nn : N\nn = Zero", nil},
+		{"rigid type variable", "r : a -> a\nr x = Zero", []report{typeError(TypeMismatch + ": expected `a`, got `Nat`")}},
+		{"solved type variable", "ys : List Nat\nys = Cons True Nil", []report{typeError(TypeMismatch + ": expected `List Nat`, got `List Bool`")}},
+		{"solved implicit", "len : List a -> Nat\n\nm : Nat\nm = len (Cons Zero Nil)", nil},
+		{"unsolved implicit", "len : List a -> Nat\n\nm : Nat\nm = len Nil", []report{typeError(UnsolvedImplicit + ": `a`")}},
+		{"default implicit", "width : {w : Nat := Succ Zero} -> Nat\n\nk : Nat\nk = width", nil},
+		{"default mismatch", "width : {w : Nat := True} -> Nat", []report{typeError(TypeMismatch + ": expected `Nat`, got `Bool`")}},
+		{"unsolved without default", "size : {s : Type} -> Nat\n\nk : Nat\nk = size", []report{typeError(UnsolvedImplicit + ": `s : Type`")}},
+		{"instantiation", "const : a -> b -> a\nconst x y = x\n\nc : List Bool\nc = Cons (const True Zero) Nil", nil},
+		{"untyped", "u = Succ Zero", nil},
+		{"untyped mismatch", "u = Succ True", []report{typeError(TypeMismatch + ": expected `Nat`, got `Bool`")}},
+		{"untyped not a function", "u = Zero Zero", []report{typeError(IllegalApplication + ": `Nat`")}},
+		{"untyped use", "u = Succ Zero\n\nb : Bool\nb = u", []report{typeError(TypeMismatch + ": expected `Bool`, got `Nat`")}},
+		{"untyped params", "twice f x = f (f x)\n\nn : Nat\nn = twice Succ Zero", nil},
+		{"untyped params mismatch", "twice f x = f (f x)\n\nn : Nat\nn = twice Succ True", []report{typeError(TypeMismatch + ": expected `Nat`, got `Bool`")}},
+		{"untyped polymorphic", "id x = x\n\nb : Bool\nb = id True\n\nn : Nat\nn = id Zero", nil},
+		{"untyped unsolved implicit", "len : List a -> Nat\n\nm = len Nil", []report{typeError(UnsolvedImplicit + ": `a`")}},
+		{"untyped where", "w : Nat\nw = y where (\n  y = Zero\n)", nil},
+		{"untyped where mismatch", "w : Bool\nw = y where (\n  y = Zero\n)", []report{typeError(TypeMismatch + ": expected `Bool`, got `Nat`")}},
+		{"untyped clauses", "u = Zero\nu = Zero", []report{warning(UnreachableClause)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectReports(t, checkSource(t, typeCheckPrelude+test.source), test.expected...)
+		})
+	}
+}
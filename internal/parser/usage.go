@@ -0,0 +1,309 @@
+// =================================================================================================
+// usage checking: counts the uses of `once` and `erase` variables
+// =================================================================================================
+
+package parser
+
+import (
+	"fmt"
+
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/internal/symbol"
+)
+
+// how many times a variable is used at runtime: the fewest and most times it's used across the
+// branches of a body
+type usage struct {
+	min, max int
+	// where the variable is used, in order
+	uses []api.Positioned
+}
+
+// usage of a term followed by the usage of another term
+func (u usage) then(v usage) usage {
+	return usage{min: u.min + v.min, max: u.max + v.max, uses: append(u.uses, v.uses...)}
+}
+
+// usage of a branch or, alternatively, the usage of another branch
+func (u usage) or(v usage) usage {
+	return usage{min: min(u.min, v.min), max: max(u.max, v.max), uses: append(u.uses, v.uses...)}
+}
+
+// a variable bound with a multiplicity other than `Unlimited`
+type restricted struct {
+	binder       name
+	multiplicity int8
+}
+
+// returns the binder of `pat` if `pat` is a variable, e.g., `x` or `{x}`
+func patternVariable(pat pattern) (name, bool) {
+	switch p := pat.(type) {
+	case name:
+		return p, isLowerName(p)
+	case patternEnclosed:
+		if p.Len() == 1 {
+			return patternVariable(p.Head())
+		}
+	}
+	return name{}, false
+}
+
+// records `param` as restricted when it's a variable bound with the multiplicity `mult`
+func restrict(rs []restricted, param pattern, mult int8) []restricted {
+	if n, isVar := patternVariable(param); isVar && mult != symbol.Unlimited {
+		return append(rs, restricted{n, mult})
+	}
+	return rs
+}
+
+// records the lambda binder `b` as restricted when it's a variable bound with the multiplicity `mult`
+func restrictBinder(rs []restricted, b binder, mult int8) []restricted {
+	if id, pat, isPattern := b.Break(); isPattern {
+		return restrict(rs, pat, mult)
+	} else if n := identAsName(id); isLowerName(n) {
+		return restrict(rs, n, mult)
+	}
+	return rs
+}
+
+// records the position of an argument passed for an erased binder--uses inside of it are not runtime
+// uses
+func (c *typeChecker) erasedArgument(arg api.Positioned) {
+	start, end := arg.Pos()
+	c.erased[[2]int{start, end}] = true
+}
+
+func (c *typeChecker) isErasedArgument(arg api.Positioned) bool {
+	start, end := arg.Pos()
+	return c.erased[[2]int{start, end}]
+}
+
+// reports each restricted variable that `count` shows is used too many or too few times
+func (c *typeChecker) checkUsage(rs []restricted, count func(x string) (usage, bool)) {
+	for _, r := range rs {
+		x := nameString(r.binder)
+		u, possible := count(x)
+		if !possible {
+			continue // body is impossible, so nothing is ever used
+		}
+
+		if r.multiplicity == symbol.Erase {
+			for _, use := range u.uses {
+				c.errorAt(IllegalErasedUse+": `"+x+"`", use)
+			}
+		} else if u.max > 1 {
+			c.errorAt(IllegalLinearUse+": "+fmt.Sprintf("`%s` is used %d times", x, u.max), u.uses[1])
+		} else if u.min < 1 {
+			c.errorAt(IllegalLinearUse+": `"+x+"` is not used", r.binder)
+		}
+	}
+}
+
+// counts the runtime uses of the variable `x` in `body`, returning false if `body` is impossible
+func (c *typeChecker) defBodyUsage(x string, body defBody) (u usage, possible bool) {
+	_, p, possible := body.Break()
+	if !possible {
+		return u, false
+	}
+
+	if where, just := p.Snd().Break(); just {
+		if bindsElements(where.Elements(), x) {
+			return u, true
+		}
+		for _, elem := range where.Elements() {
+			if d, isDef := elem.(def); isDef {
+				if v, ok := c.whereDefUsage(x, d); ok {
+					u = u.then(v)
+				}
+			}
+		}
+	}
+
+	with, e, isExpr := p.Fst().Break()
+	if isExpr {
+		return u.then(c.exprUsage(x, e)), true
+	}
+	return u.then(c.withUsage(x, with)), true
+}
+
+func (c *typeChecker) whereDefUsage(x string, d def) (usage, bool) {
+	_, params, ok := definedName(d.pattern)
+	for _, param := range params {
+		if binds(param, x) {
+			return usage{}, false
+		}
+	}
+	if !ok && binds(d.pattern, x) {
+		return usage{}, false
+	}
+	return c.defBodyUsage(x, d.defBody)
+}
+
+// returns true iff one of the where clause elements `elems` defines `x`
+func bindsElements(elems []mainElement, x string) bool {
+	for _, elem := range elems {
+		switch e := elem.(type) {
+		case typing:
+			if nameString(e.typing.Fst()) == x {
+				return true
+			}
+		case def:
+			if n, _, ok := definedName(e.pattern); ok && nameString(n) == x {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// the arms of a with clause repeat the patterns of the definition they refine, so `x` is not
+// shadowed by them
+func (c *typeChecker) withUsage(x string, with withClause) usage {
+	u := patternUsage(x, with.Fst())
+	var arms usage
+	first := true
+	for _, arm := range with.Snd().Elements() {
+		v, possible := c.defBodyUsage(x, arm.Snd())
+		if !possible {
+			continue
+		} else if first {
+			arms, first = v, false
+		} else {
+			arms = arms.or(v)
+		}
+	}
+	return u.then(arms)
+}
+
+// counts uses of `x` in a pattern that's in an expression position, e.g., a case scrutinee
+func patternUsage(x string, pat pattern) usage {
+	switch p := pat.(type) {
+	case name:
+		if nameString(p) == x {
+			return usage{min: 1, max: 1, uses: []api.Positioned{p}}
+		}
+	case patternApp:
+		u := patternUsage(x, p.Fst())
+		for _, arg := range p.Snd().Elements() {
+			u = u.then(patternUsage(x, arg))
+		}
+		return u
+	case patternEnclosed:
+		var u usage
+		for _, elem := range p.Elements() {
+			u = u.then(patternUsage(x, elem))
+		}
+		return u
+	}
+	return usage{}
+}
+
+// returns true iff `pat` binds the variable `x`
+func binds(pat pattern, x string) bool {
+	switch p := pat.(type) {
+	case name:
+		return isLowerName(p) && nameString(p) == x
+	case patternApp:
+		if binds(p.Fst(), x) {
+			return true
+		}
+		for _, arg := range p.Snd().Elements() {
+			if binds(arg, x) {
+				return true
+			}
+		}
+	case patternEnclosed:
+		for _, elem := range p.Elements() {
+			if binds(elem, x) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func bindsBinder(b binder, x string) bool {
+	id, pat, isPattern := b.Break()
+	if isPattern {
+		return binds(pat, x)
+	}
+	return nameString(identAsName(id)) == x
+}
+
+// counts the runtime uses of the variable `x` in `e`
+func (c *typeChecker) exprUsage(x string, e expr) usage {
+	if c.isErasedArgument(e) {
+		return usage{}
+	}
+
+	switch y := e.(type) {
+	case name:
+		if nameString(y) == x {
+			return usage{min: 1, max: 1, uses: []api.Positioned{y}}
+		}
+	case exprApp:
+		var u usage
+		if _, _, ok := qualifiedName(y.Fst(), y.Snd().Head()); !ok {
+			u = c.exprUsage(x, y.Fst())
+		}
+		for _, arg := range y.Snd().Elements() {
+			u = u.then(c.exprUsage(x, arg))
+		}
+		return u
+	case lambdaAbstraction:
+		for _, binder := range y.Fst().Elements() {
+			if b, _, isWildcard := binder.Either.Break(); !isWildcard && bindsBinder(b, x) {
+				return usage{}
+			}
+		}
+		return c.exprUsage(x, y.Snd())
+	case letExpr:
+		return c.letUsage(x, y)
+	case caseExpr:
+		return c.caseUsage(x, y)
+	}
+	return usage{}
+}
+
+func (c *typeChecker) letUsage(x string, let letExpr) usage {
+	members := let.Fst().Elements()
+	for _, member := range members {
+		bound, ty, isTyping := member.Break()
+		if isTyping && nameString(ty.Fst().typing.Fst()) == x || !isTyping && bindsBinder(bound.Fst(), x) {
+			return usage{} // let binding groups are recursive, so `x` is shadowed everywhere
+		}
+	}
+
+	var u usage
+	for _, member := range members {
+		bound, ty, isTyping := member.Break()
+		if !isTyping {
+			u = u.then(c.exprUsage(x, bound.Snd()))
+		} else if e, just := ty.Snd().Break(); just {
+			u = u.then(c.exprUsage(x, e))
+		}
+	}
+	return u.then(c.exprUsage(x, let.Snd()))
+}
+
+func (c *typeChecker) caseUsage(x string, cs caseExpr) usage {
+	u := patternUsage(x, cs.Fst())
+	var arms usage
+	first := true
+	for _, arm := range cs.Snd().Elements() {
+		var v usage
+		if !binds(arm.Fst(), x) {
+			var possible bool
+			if v, possible = c.defBodyUsage(x, arm.Snd()); !possible {
+				continue
+			}
+		}
+
+		if first {
+			arms, first = v, false
+		} else {
+			arms = arms.or(v)
+		}
+	}
+	return u.then(arms)
+}
@@ -0,0 +1,32 @@
+//go:build test
+// +build test
+
+package parser
+
+import "testing"
+
+func TestCheckUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []report
+	}{
+		{"once used once", "f : (once x : Nat) -> Nat\nf x = Succ x", nil},
+		{"once unused", "f : (once x : Nat) -> Nat\nf x = Zero", []report{typeError(IllegalLinearUse + ": `x` is not used")}},
+		{"once used twice", "f : (once x : Nat) -> List Nat\nf x = Cons x (Cons x Nil)", []report{typeError(IllegalLinearUse + ": `x` is used 2 times")}},
+		{"once in each branch", "f : (once x : Nat) -> Bool -> Nat\nf x b = case b of (\n  True => x\n  False => Succ x\n)", nil},
+		{"once in one branch", "f : (once x : Nat) -> Bool -> Nat\nf x b = case b of (\n  True => x\n  False => Zero\n)", []report{typeError(IllegalLinearUse + ": `x` is not used")}},
+		{"once shadowed", "f : (once x : Nat) -> Nat\nf x = (\\x => x) x", []report{warning(ShadowedName + ": x")}},
+		{"once lambda", "f : (once x : Nat) -> Nat\nf = \\x => Succ (Succ x)", nil},
+		{"once lambda unused", "f : (once x : Nat) -> Nat\nf = \\x => Zero", []report{typeError(IllegalLinearUse + ": `x` is not used")}},
+		{"erase unused", "f : {erase n : Nat} -> Nat -> Nat\nf {n} m = m", nil},
+		{"erase used", "f : {erase n : Nat} -> Nat -> Nat\nf {n} m = n", []report{typeError(IllegalErasedUse + ": `n`")}},
+		{"erase passed erased", "g : (erase k : Nat) -> Nat\n\nf : {erase n : Nat} -> Nat\nf {n} = g n", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectReports(t, checkSource(t, typeCheckPrelude+test.source), test.expected...)
+		})
+	}
+}
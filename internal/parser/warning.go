@@ -0,0 +1,9 @@
+package parser
+
+const (
+	NonExhaustivePatterns     = "patterns are not exhaustive"             // non-exhaustive-patterns
+	ReachableImpossibleClause = "clause marked impossible can be matched" // reachable-impossible-clause
+	ShadowedName              = "name shadows an existing binding"        // shadowed-name
+	UnknownAnnotation         = "annotation is not registered"            // unknown-annotation
+	UnreachableClause         = "clause is never reached"                 // unreachable-clause
+)
@@ -0,0 +1,219 @@
+// =================================================================================================
+// with clauses: checks and lowers `with ... of` clauses, which match on an intermediate value and
+// refine the patterns of the clause they belong to
+// =================================================================================================
+
+package parser
+
+import (
+	"github.com/petersalex27/yew/api"
+	"github.com/petersalex27/yew/internal/core"
+	"github.com/petersalex27/yew/internal/symbol"
+)
+
+// name of the function a with clause is lowered into; it's a keyword, so it never shadows a name
+// defined in source
+const withFunction = "with"
+
+// the left-hand side the arms of a with clause refine: the parameters of a definition, or the
+// pattern of a case or with arm
+type refinable struct {
+	params []pattern
+	// type of each parameter, nil when unknown
+	domains []api.Type
+	// returns the parameters of a refined left-hand side, e.g., `[Zero]` for `f Zero` refining `f n`
+	refine func(pattern) ([]pattern, bool)
+}
+
+// left-hand side of a definition of `n`; its arms refine it by repeating the definition's head,
+// e.g., `f Zero | True => ...`
+func defLhs(n name, params []pattern, domains []api.Type) refinable {
+	refine := func(pat pattern) ([]pattern, bool) {
+		m, refined, ok := definedName(pat)
+		return refined, ok && nameString(m) == nameString(n) && len(refined) == len(params)
+	}
+	return refinable{params, domains, refine}
+}
+
+// left-hand side of a case or with arm matching a value of type `ty`
+func armLhs(pat pattern, ty api.Type) refinable {
+	refine := func(refined pattern) ([]pattern, bool) { return []pattern{refined}, true }
+	return refinable{[]pattern{pat}, []api.Type{ty}, refine}
+}
+
+// splits the left-hand side of a with arm into its refinement of the clause (if any) and its pattern
+func withArm(arm withClauseArm) (refined pattern, pat pattern, isRefined bool) {
+	pat, both, isRefined := arm.Fst().Break()
+	if isRefined {
+		return both.Fst(), both.Snd(), true
+	}
+	return nil, pat, false
+}
+
+// returns true iff `refined` only matches values `pat` matches
+func refines(pat, refined core.Pattern) bool {
+	switch p := pat.(type) {
+	case core.PCon:
+		r, isCon := refined.(core.PCon)
+		if !isCon {
+			return irrefutablePattern(refined) && !isVariablePattern(refined)
+		} else if r.Name != p.Name || len(r.Args) != len(p.Args) {
+			return false
+		}
+		for i, arg := range p.Args {
+			if !refines(arg, r.Args[i]) {
+				return false
+			}
+		}
+		return true
+	case core.PLit:
+		r, isLit := refined.(core.PLit)
+		return (isLit && r.Lit == p.Lit) || (irrefutablePattern(refined) && !isVariablePattern(refined))
+	}
+	return true
+}
+
+func irrefutablePattern(pat core.Pattern) bool {
+	switch pat.(type) {
+	case core.PVar, core.PWild, core.PAccess:
+		return true
+	}
+	return false
+}
+
+func isVariablePattern(pat core.Pattern) bool {
+	_, isVar := pat.(core.PVar)
+	return isVar
+}
+
+// checks a with clause: the arms match the scrutinee, and each arm's body is checked against
+// `expected` with the scrutinee abstracted out and replaced by the arm's pattern, e.g., with
+// `with n of (Zero => ...)`, the goal `Vec n a` becomes `Vec Zero a` in the arm
+//
+// only a variable scrutinee is abstracted out of the goal
+func (c *typeChecker) withClause(w withClause, expected api.Type, lhs refinable) {
+	scrutinee := c.synthPattern(w.Fst())
+	abstracted, isVar := symbol.IsVariable(c.patternAsType(w.Fst()))
+	for _, arm := range w.Snd().Elements() {
+		c.types.Enter()
+		refined, pat, isRefined := withArm(arm)
+		if isRefined {
+			c.refine(lhs, refined)
+		}
+
+		restore := c.expectingFrom(w.Fst())
+		c.checkPattern(pat, scrutinee)
+		restore()
+
+		goal := expected
+		if isVar {
+			goal = symbol.Substitute(expected, abstracted, c.patternAsType(pat))
+		}
+		c.defBody(arm.Snd(), goal, armLhs(pat, scrutinee))
+		c.types.Exit()
+	}
+	c.coverWith(w, lhs, scrutinee)
+}
+
+// checks that `refined` refines the left-hand side `lhs`, binding the variables of `refined`
+func (c *typeChecker) refine(lhs refinable, refined pattern) {
+	params, ok := lhs.refine(refined)
+	if !ok {
+		c.errorAt(IllegalRefinement, refined)
+		c.bindUnknown(refined)
+		return
+	}
+
+	for i, param := range params {
+		if !refines(lowerPattern(lhs.params[i]), lowerPattern(param)) {
+			c.errorAt(IllegalRefinement, param)
+		}
+		if lhs.domains[i] == nil {
+			c.bindUnknown(param)
+		} else {
+			c.checkPattern(param, lhs.domains[i])
+		}
+	}
+}
+
+// lowers a with clause into a local function applied to the variables of `lhs` and the scrutinee;
+// each arm is a clause of the function, matching its refinement of each variable and its pattern,
+// so an arm whose refinement doesn't match falls through to the next arm
+func lowerWith(w withClause, lhs refinable) core.Term {
+	vars := refinableVariables(lhs)
+	f := core.Func{Name: withFunction}
+	for _, arm := range w.Snd().Elements() {
+		if _, possible, isPossible := arm.Snd().Break(); isPossible {
+			_, pat, _ := withArm(arm)
+			body := lowerBody(possible, armLhs(pat, nil))
+			f.Clauses = append(f.Clauses, core.Clause{Patterns: withArmPatterns(arm, lhs, vars), Body: body})
+		}
+	}
+
+	args := make([]core.Term, 0, len(vars)+1)
+	for _, x := range vars {
+		args = append(args, core.Var{Name: x})
+	}
+	args = append(args, lowerPatternTerm(w.Fst()))
+	return core.Let{Funcs: []core.Func{f}, Body: core.App{Fun: core.Var{Name: withFunction}, Args: args}}
+}
+
+// returns the variables bound by the explicit parameters of `lhs`, in order
+func refinableVariables(lhs refinable) (vars []string) {
+	for _, param := range lhs.params {
+		if enclosed, isEnclosed := param.(patternEnclosed); !isEnclosed || !enclosed.implicit {
+			vars = coreVariables(lowerPattern(param), vars)
+		}
+	}
+	return vars
+}
+
+// returns the patterns of the clause a with arm is lowered into: the arm's refinement of each of
+// `vars`--the variables of `lhs`--followed by the arm's pattern
+func withArmPatterns(arm withClauseArm, lhs refinable, vars []string) []core.Pattern {
+	refinements := make(map[string]core.Pattern)
+	refined, pat, isRefined := withArm(arm)
+	if isRefined {
+		params, ok := lhs.refine(refined)
+		for i := 0; ok && i < len(params); i++ {
+			refineVariables(lowerPattern(lhs.params[i]), lowerPattern(params[i]), refinements)
+		}
+	}
+
+	patterns := make([]core.Pattern, 0, len(vars)+1)
+	for _, x := range vars {
+		if r, found := refinements[x]; found {
+			patterns = append(patterns, r)
+		} else {
+			patterns = append(patterns, core.PVar{Name: x})
+		}
+	}
+	return append(patterns, lowerPattern(pat))
+}
+
+// appends the variables `pat` binds to `vars`, in order
+func coreVariables(pat core.Pattern, vars []string) []string {
+	switch p := pat.(type) {
+	case core.PVar:
+		return append(vars, p.Name)
+	case core.PCon:
+		for _, arg := range p.Args {
+			vars = coreVariables(arg, vars)
+		}
+	}
+	return vars
+}
+
+// records, for each variable of `pat`, the part of `refined` in its place
+func refineVariables(pat, refined core.Pattern, refinements map[string]core.Pattern) {
+	switch p := pat.(type) {
+	case core.PVar:
+		refinements[p.Name] = refined
+	case core.PCon:
+		if r, isCon := refined.(core.PCon); isCon && len(r.Args) == len(p.Args) {
+			for i, arg := range p.Args {
+				refineVariables(arg, r.Args[i], refinements)
+			}
+		}
+	}
+}
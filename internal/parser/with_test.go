@@ -0,0 +1,37 @@
+//go:build test
+// +build test
+
+package parser
+
+import "testing"
+
+const withPrelude = `Is : Bool -> Type where (
+  Yes : Is True
+  No : Is False
+)
+
+`
+
+func TestWithClause(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []report
+	}{
+		{"abstracted goal", "decide : {t : Type} -> (b : Bool) -> Is b\ndecide b with b of (\n  True => Yes\n  False => No\n)", nil},
+		{"abstracted goal mismatch", "decide : {t : Type} -> (b : Bool) -> Is b\ndecide b with b of (\n  True => No\n  False => No\n)", []report{typeError(TypeMismatch + ": expected `Is True`, got `Is False`")}},
+		{"refined", "pred : Nat -> Bool -> Nat\npred n b with b of (\n  pred (Succ m) _ | True => m\n  _ => Zero\n)", nil},
+		{"refined fall through", "f : Bool -> Bool\nf b with b of (\n  f True | True => True\n  f False | True => True\n  _ => False\n)", nil},
+		{"refinement of another function", "f : Bool -> Bool\nf b with b of (\n  g True | True => True\n  _ => False\n)", []report{typeError(IllegalRefinement)}},
+		{"refinement of a constructor", "f : Nat -> Bool -> Nat\nf Zero b with b of (\n  f (Succ n) _ | True => n\n  _ => Zero\n)", []report{typeError(IllegalRefinement), warning(NonExhaustivePatterns + ": missing `f (Succ _) _`")}},
+		{"missing arm", "f : Bool -> Bool\nf b with b of (\n  True => False\n)", []report{warning(NonExhaustivePatterns + ": missing `False`")}},
+		{"missing refined arm", "f : Bool -> Bool\nf b with b of (\n  f True | True => True\n  f False | True => True\n)", []report{warning(NonExhaustivePatterns + ": missing `True | False`")}},
+		{"unreachable arm", "f : Bool -> Bool\nf b with b of (\n  _ => False\n  True => True\n)", []report{warning(UnreachableClause)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectReports(t, checkSource(t, typeCheckPrelude+withPrelude+test.source), test.expected...)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package source
+
+import (
+	"bytes"
+	"strings"
+)
+
+// extension of literate Yew files
+const LiterateExtension = ".lyew"
+
+// where a line of a literate document is
+type literateState byte
+
+const (
+	inProse literateState = iota
+	// within a fence opened by "```" or "```yew"
+	inCode
+	// within a fence opened for another language, e.g., "```haskell"
+	inForeignCode
+)
+
+// Literate unliterates a literate Yew document, i.e., prose with code marked either by bird tracks
+// (lines beginning with `>`) or by fences (lines beginning with "```", optionally followed by
+// `yew`).
+//
+// the document is unliterated by replacing each byte of prose, bird track, and fence with a space,
+// so the code keeps the line and column it has in the document
+//
+// the zero value is ready to use; it keeps track of whether it's within a fence, so a document can
+// be unliterated a piece at a time, e.g., line by line
+type Literate struct{ state literateState }
+
+// returns true iff `path` names a literate Yew file
+func IsLiterate(path string) bool { return strings.HasSuffix(path, LiterateExtension) }
+
+// returns `document` with everything but its code replaced by spaces
+func (lit *Literate) Unliterate(document string) string {
+	b := []byte(document)
+	for start := 0; start < len(b); {
+		end := bytes.IndexByte(b[start:], '\n')
+		if end < 0 {
+			end = len(b)
+		} else {
+			end += start
+		}
+		lit.line(b[start:end])
+		start = end + 1
+	}
+	return string(b)
+}
+
+// Code unliterates `document` like `Unliterate`, but removes each bird track (along with the space
+// following it) rather than blanking it, so bird tracked code isn't indented by its track, e.g., for
+// recording the code as Yew source--the inverse of `BirdTrack`
+//
+// unlike `Unliterate`, the columns of bird tracked code aren't kept
+func (lit *Literate) Code(document string) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(document, "\n") {
+		tracked := lit.state == inProse && strings.HasPrefix(line, ">")
+		code := lit.Unliterate(line)
+		if tracked {
+			code = strings.TrimPrefix(code[1:], " ")
+		}
+		b.WriteString(code)
+	}
+	return b.String()
+}
+
+// unliterates `line` in place
+func (lit *Literate) line(line []byte) {
+	if fence, isFence := bytes.CutPrefix(line, []byte("```")); isFence {
+		language := string(bytes.TrimSpace(fence))
+		switch {
+		case lit.state != inProse && language == "":
+			lit.state = inProse
+		case lit.state == inProse && (language == "" || language == "yew"):
+			lit.state = inCode
+		case lit.state == inProse:
+			lit.state = inForeignCode
+		}
+		blank(line)
+		return
+	}
+
+	switch {
+	case lit.state == inCode:
+	case lit.state == inProse && len(line) != 0 && line[0] == '>':
+		line[0] = ' '
+	default:
+		blank(line)
+	}
+}
+
+// replaces each byte of `line` with a space, leaving any carriage return
+func blank(line []byte) {
+	for i := range line {
+		if line[i] != '\r' {
+			line[i] = ' '
+		}
+	}
+}
+
+// returns `code` as a literate document, marking each non-empty line with a bird track
+func BirdTrack(code string) string {
+	lines := strings.SplitAfter(code, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = "> " + line
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,97 @@
+package source
+
+import (
+	"testing"
+)
+
+func TestUnliterate(t *testing.T) {
+	tests := []struct {
+		name, document, expected string
+	}{
+		{"prose", "some prose\n", "          \n"},
+		{"bird tracks", "prose\n> x = 1\n>y = x\n", "     \n  x = 1\n y = x\n"},
+		{"fence", "```\nx = 1\n```\n", "   \nx = 1\n   \n"},
+		{"yew fence", "```yew\nx = 1\n```", "      \nx = 1\n   "},
+		{"foreign fence", "```haskell\nx = 1\n```\n> y = 2", "          \n     \n   \n  y = 2"},
+		{"bird track in fence", "```\n> x\n```", "   \n> x\n   "},
+		{"carriage returns", "prose\r\n> x\r\n", "     \r\n  x\r\n"},
+		{"unicode prose", "λ\n", "  \n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := new(Literate).Unliterate(test.document)
+			if actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+			if len(actual) != len(test.document) {
+				t.Errorf("expected positions to be kept, but the length changed from %d to %d", len(test.document), len(actual))
+			}
+		})
+	}
+}
+
+func TestUnliterateLineByLine(t *testing.T) {
+	lit := new(Literate)
+	lines := []string{"```\n", "x = 1\n", "```\n", "prose\n"}
+	expected := []string{"   \n", "x = 1\n", "   \n", "     \n"}
+	for i, line := range lines {
+		if actual := lit.Unliterate(line); actual != expected[i] {
+			t.Errorf("line %d: expected %q, got %q", i+1, expected[i], actual)
+		}
+	}
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name, document, expected string
+	}{
+		{"bird tracks", "prose\n> x = 1\n>y = x\n", "     \nx = 1\ny = x\n"},
+		{"indented bird track", ">   x\n", "  x\n"},
+		{"empty bird track", ">\n", "\n"},
+		{"fence", "```\n  x = 1\n```\n", "   \n  x = 1\n   \n"},
+		{"bird track in fence", "```\n> x\n```", "   \n> x\n   "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := new(Literate).Code(test.document); actual != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, actual)
+			}
+		})
+	}
+
+	code := "x = 1\n\n  y = x\n"
+	if actual := new(Literate).Code(BirdTrack(code)); actual != code {
+		t.Errorf("expected bird tracked code to be its own code, got %q", actual)
+	}
+}
+
+func TestBirdTrack(t *testing.T) {
+	code := "x = 1\n\ny = x\n"
+	expected := "> x = 1\n\n> y = x\n"
+	if actual := BirdTrack(code); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if unliterated := new(Literate).Unliterate(expected); unliterated != "  x = 1\n\n  y = x\n" {
+		t.Errorf("expected bird tracked code to unliterate to itself, got %q", unliterated)
+	}
+}
+
+func TestSetLiterate(t *testing.T) {
+	document := "prose\n> x = 1\n"
+	src := (SourceCode{}).Set(mockSource{path: "notes" + LiterateExtension, content: document})
+	if code := src.String(); code != "     \n  x = 1\n" {
+		t.Errorf("expected the code of the document, got %q", code)
+	}
+
+	literate := src.(SourceCode)
+	literate.AppendSource("more prose\n> y = x\n")
+	if code := literate.String(); code != "     \n  x = 1\n          \n  y = x\n" {
+		t.Errorf("expected the code of the appended document, got %q", code)
+	}
+
+	if windowed := literate.PrepareForWindowing().String(); windowed != document+"more prose\n> y = x\n\n" {
+		t.Errorf("expected windows to show the document, got %q", windowed)
+	}
+}
@@ -14,6 +14,10 @@ type SourceCode struct {
 	path string
 	// source file as an array of strings for each non-empty line, does not include newline chars
 	Source []byte
+	// the literate document `Source` was unliterated from, nil if the source isn't literate
+	document []byte
+	// unliterates what's appended to a literate source
+	literate Literate
 	// records end (exclusive) position for all lines n at index n-1.
 	//
 	// for example, given
@@ -40,15 +44,23 @@ func (src SourceCode) Copy() SourceCode {
 	out := SourceCode{
 		path:         src.path,
 		Source:       source,
+		literate:     src.literate,
 		endPositions: positions,
 	}
 	copy(out.Source, src.Source)
 	copy(out.endPositions, src.endPositions)
+	if src.document != nil {
+		out.document = append([]byte{}, src.document...)
+	}
 	return out
 }
 
 // IMPORTANT: This mutates the source code by appending the given string to the end of the source code.
 func (src *SourceCode) AppendSource(addition string) {
+	if src.document != nil {
+		src.document = append(src.document, []byte(addition)...)
+		addition = src.literate.Unliterate(addition)
+	}
 	src.Source = append(src.Source, []byte(addition)...)
 	src.endPositions = makeEndPositions(src.endPositions, string(src.Source))
 }
@@ -57,11 +69,16 @@ func (src SourceCode) Path() string {
 	return src.path
 }
 
+// windows of a literate source show the document, whose positions are the same as its code's
 func (src SourceCode) PrepareForWindowing() api.SourceCode {
+	content := src.Source
+	if src.document != nil {
+		content = src.document
+	}
 	// add final newline--this is necessary for (SourceCode).window to work correctly.
 	// We want empty files to contain a single--but, importantly--empty line
-	freeSource := util.FreeSource(src.path, string(src.Source)+"\n")
-	return (SourceCode{}).Set(freeSource)
+	freeSource := util.FreeSource(src.path, string(content)+"\n")
+	return (SourceCode{}).set(freeSource)
 }
 
 func makeEndPositions(dest []int, content string) []int {
@@ -86,10 +103,22 @@ func makeEndPositions(dest []int, content string) []int {
 	return dest
 }
 
+// sets the source to `source`, unliterating it if its path names a literate Yew file
 func (src SourceCode) Set(source api.Source) api.SourceCode {
+	if !IsLiterate(source.Path()) {
+		return src.set(source)
+	}
+	src.literate = Literate{}
+	out := src.set(util.FreeSource(source.Path(), src.literate.Unliterate(source.String())))
+	out.document = []byte(source.String())
+	return out
+}
+
+func (src SourceCode) set(source api.Source) SourceCode {
 	content := source.String()
 	src.path = source.Path()
 	src.Source = []byte(content)
+	src.document = nil
 	src.endPositions = makeEndPositions(nil, content)
 	return src
 }
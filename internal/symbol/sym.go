@@ -26,14 +26,14 @@ const (
 	Unlimited int8 = 2
 )
 
-func declareWithMult(mult int8, x api.Token, ty api.Type) sym {
+func declareWithMult(mult int8, name string, ty api.Type) sym {
 	return sym{
 		multiplicity: min(max(mult, 0), Unlimited), // ensure that the multiplicity is valid
-		name:         x.String(),
+		name:         name,
 		typ:          ty,
 	}
 }
 
-func declare(x api.Token, ty api.Type) sym {
-	return declareWithMult(Unlimited, x, ty)
+func declare(name string, ty api.Type) sym {
+	return declareWithMult(Unlimited, name, ty)
 }
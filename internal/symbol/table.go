@@ -18,20 +18,27 @@ type Table struct {
 	symbols    *stack.MapStack[string, sym]
 }
 
-// create a new symbol table
+// create a new symbol table with a single (global) scope
 func New() *Table {
-	return &Table{
+	t := &Table{
 		symbols: stack.NewMap[string, sym](),
 	}
+	t.symbols.Push(make(map[string]sym))
+	return t
 }
 
-// only enterable from Declare
-func (t *Table) declare(tok api.Token) {
+// only enterable from Declare and DeclareName
+//
+// declares `name` in the current scope, giving it a fresh free variable as its type
+//
+// returns false iff `name` is already declared in the current scope
+func (t *Table) declare(name string) (success bool) {
 	ty := t.newVar(t.freeCounter)
-	x := declare(tok, ty) // create a new symbol
-	/*success*/_ = t.symbols.MapNew(tok.String(), x)
-	t.freeCounter++
-	panic("TODO: implement") // TODO: implement
+	x := declare(name, ty) // create a new symbol
+	if success = t.symbols.MapNew(name, x); success {
+		t.freeCounter++
+	}
+	return success
 }
 
 // create a new free variable with a unique name
@@ -48,10 +55,10 @@ func (t *Table) newVar(num int) variable {
 
 	// try to avoid a num >= 10
 	offset := (num / 10) % 26 // advance the letter every 10 variables
-	num = num % 10
+	digit := num % 10
 	var out = append(make([]byte, 0, 3), '~', 'a'+byte(offset))
-	if num > 0 { // don't use '~a0', just use '~a'
-		out = append(out, byte('0'+num)) // num guaranteed to be < 10
+	if digit > 0 { // don't use '~a0', just use '~a'
+		out = append(out, byte('0'+digit)) // digit guaranteed to be < 10
 	}
 	str := string(out)
 	if num >= 260 { // add ' for every 260 variables
@@ -60,8 +67,82 @@ func (t *Table) newVar(num int) variable {
 	return variable(str)
 }
 
-func (t *Table) Declare(tok api.Token) {
+// declares the symbol represented by `tok` in the current scope
+//
+// returns false iff the symbol is already declared in the current scope
+func (t *Table) Declare(tok api.Token) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.declare(tok.String())
+}
+
+// like `Declare`, but for symbols without a token representation (e.g., package qualifiers)
+func (t *Table) DeclareName(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.declare(name)
+}
+
+// declares `name` with type `ty` in the current scope
+//
+// returns false iff the symbol is already declared in the current scope
+func (t *Table) DeclareTyped(name string, ty api.Type) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.symbols.MapNew(name, declare(name, ty))
+}
+
+// returns a new free variable, unique to this table
+func (t *Table) Fresh() api.Type {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	v := t.newVar(t.freeCounter)
+	t.freeCounter++
+	return v
+}
+
+// searches for `name` starting from the current scope and moving outward, returning the type of
+// the symbol if found
+func (t *Table) Lookup(name string) (ty api.Type, found bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	x, found := t.symbols.Find(name)
+	return x.typ, found
+}
+
+// returns true iff `name` is declared in the current scope
+func (t *Table) Declared(name string) bool {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.declare(tok)
+	return t.symbols.Exists(name)
+}
+
+// returns true iff declaring `name` in the current scope would shadow a symbol declared in an
+// enclosing scope
+func (t *Table) Shadows(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.symbols.Exists(name) {
+		return false
+	}
+	_, found := t.symbols.Find(name)
+	return found
+}
+
+// enter a new scope
+func (t *Table) Enter() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.symbols.Push(make(map[string]sym))
+}
+
+// exit the current scope, discarding all symbols declared in it
+//
+// the global scope is never exited
+func (t *Table) Exit() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.symbols.Len() > 1 {
+		t.symbols.Pop()
+	}
 }
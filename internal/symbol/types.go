@@ -1,13 +1,25 @@
 package symbol
 
-import "github.com/petersalex27/yew/api"
+import (
+	"slices"
+	"strings"
+
+	"github.com/petersalex27/yew/api"
+)
 
 type (
 	variable string
 
 	Pi struct {
-		domain sym
-		target api.Type
+		// when true, arguments for the domain are passed implicitly, e.g., `{a : Type} -> a -> a`
+		implicit bool
+		// when true, the domain is a constraint satisfied by a spec instance, e.g., `Eq a => a`
+		constraint bool
+		domain     sym
+		target     api.Type
+		// argument passed for an implicit binder when no other argument can be determined, e.g., `a`
+		// in `{x : A := a} -> B`--nil if there is none
+		defaultArg api.Type
 	}
 
 	Constant struct {
@@ -80,12 +92,276 @@ func (c Constant) Apply(a api.Type) api.App {
 	return api.App{c, a}
 }
 
-// returns the name of the constant (with the namespace prepended)
+// returns the name of the constant (with the namespace prepended if it has one)
 func (c Constant) Constant() string {
+	if c.namespace == "" {
+		return c.name
+	}
 	return c.namespace + "." + c.name
 }
 
 // returns the qualified name of the constant and an empty list
 func (c Constant) Break() (head string, tail []api.Type) {
 	return c.Constant(), []api.Type{}
-}
\ No newline at end of file
+}
+
+// create a constant, e.g., `Nat` or `bool.Bool`--`namespace` is empty for unqualified constants
+func MakeConstant(namespace, name string) Constant {
+	return Constant{namespace: namespace, name: name}
+}
+
+// create a named variable
+func MakeVariable(name string) api.Type { return variable(name) }
+
+// create a pi type, e.g., `(x : A) -> B` or `{x : A} -> B`
+//
+// `binder` is empty for non-dependent function types, e.g., `A -> B`
+func MakePi(binder string, mult int8, implicit bool, domain, target api.Type) Pi {
+	return Pi{implicit: implicit, domain: declareWithMult(mult, binder, domain), target: target}
+}
+
+// returns a copy of `p` whose implicit binder defaults to `arg`
+func (p Pi) WithDefault(arg api.Type) Pi {
+	p.defaultArg = arg
+	return p
+}
+
+// returns the default argument of the pi type's binder if it has one
+func (p Pi) Default() (arg api.Type, ok bool) {
+	return p.defaultArg, p.defaultArg != nil
+}
+
+// create a constrained type, e.g., `Eq a => a -> a -> Bool`
+//
+// constraints are implicit, so their arguments--spec instances--are found by instance resolution
+func MakeConstraint(constraint, target api.Type) Pi {
+	pi := MakePi("", Unlimited, true, constraint, target)
+	pi.constraint = true
+	return pi
+}
+
+// true iff the domain of the pi type is a constraint, e.g., `Eq a` in `Eq a => a`
+func (p Pi) Constraint() bool { return p.constraint }
+
+// name bound by the pi type, possibly empty
+func (p Pi) Binder() string { return p.domain.name }
+
+// multiplicity of the pi type's binder
+func (p Pi) Multiplicity() int8 { return p.domain.multiplicity }
+
+// true iff arguments for the domain are passed implicitly
+func (p Pi) Implicit() bool { return p.implicit }
+
+func (p Pi) Domain() api.Type { return p.domain.typ }
+
+func (p Pi) Target() api.Type { return p.target }
+
+// returns the name of the variable and true iff `ty` is a variable
+func IsVariable(ty api.Type) (name string, ok bool) {
+	v, ok := ty.(variable)
+	return string(v), ok
+}
+
+// returns true iff `ty` is a free variable created by a table, e.g., `~a`
+func IsFree(ty api.Type) bool {
+	name, ok := IsVariable(ty)
+	return ok && strings.HasPrefix(name, "~")
+}
+
+// returns the names of the variables in `ty` that aren't bound by one of its pi types, in order of
+// first occurrence
+//
+// free variables created by a table (e.g., `~a`) are not included
+func FreeVariables(ty api.Type) []string {
+	return freeVariables(ty, map[string]bool{}, nil)
+}
+
+func freeVariables(ty api.Type, bound map[string]bool, out []string) []string {
+	switch t := ty.(type) {
+	case variable:
+		name := string(t)
+		if bound[name] || IsFree(t) {
+			return out
+		}
+		for _, x := range out {
+			if x == name {
+				return out
+			}
+		}
+		return append(out, name)
+	case sym:
+		return freeVariables(t.typ, bound, out)
+	case Pi:
+		out = freeVariables(t.domain.typ, bound, out)
+		if t.domain.name == "" || bound[t.domain.name] {
+			return freeVariables(t.target, bound, out)
+		}
+		bound[t.domain.name] = true
+		out = freeVariables(t.target, bound, out)
+		delete(bound, t.domain.name)
+		return out
+	case api.App:
+		for _, elem := range t {
+			out = freeVariables(elem, bound, out)
+		}
+	}
+	return out
+}
+
+// returns the names of the free variables created by a table (e.g., `~a`) in `ty`, in order of
+// first occurrence
+func Frees(ty api.Type) []string {
+	return frees(ty, nil)
+}
+
+func frees(ty api.Type, out []string) []string {
+	switch t := ty.(type) {
+	case variable:
+		if !IsFree(t) || slices.Contains(out, string(t)) {
+			return out
+		}
+		return append(out, string(t))
+	case sym:
+		return frees(t.typ, out)
+	case Pi:
+		return frees(t.target, frees(t.domain.typ, out))
+	case api.App:
+		for _, elem := range t {
+			out = frees(elem, out)
+		}
+	}
+	return out
+}
+
+// returns true iff a free variable created by a table (e.g., `~a`) occurs in `ty`
+func HasFree(ty api.Type) bool {
+	switch t := ty.(type) {
+	case variable:
+		return IsFree(t)
+	case sym:
+		return HasFree(t.typ)
+	case Pi:
+		return HasFree(t.domain.typ) || HasFree(t.target)
+	case api.App:
+		for _, elem := range t {
+			if HasFree(elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// returns true iff the variable `x` occurs free in `ty`
+func occursFree(x string, ty api.Type) bool {
+	switch t := ty.(type) {
+	case variable:
+		return string(t) == x
+	case sym:
+		return occursFree(x, t.typ)
+	case Pi:
+		if occursFree(x, t.domain.typ) || (t.defaultArg != nil && occursFree(x, t.defaultArg)) {
+			return true
+		}
+		return t.domain.name != x && occursFree(x, t.target)
+	case api.App:
+		for _, elem := range t {
+			if occursFree(x, elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// replaces each free occurrence of the variable `x` in `ty` with `s`, renaming the binders of `ty`
+// that would otherwise capture a free variable of `s`
+func Substitute(ty api.Type, x string, s api.Type) api.Type {
+	switch t := ty.(type) {
+	case variable:
+		if string(t) == x {
+			return s
+		}
+	case sym:
+		t.typ = Substitute(t.typ, x, s)
+		return t
+	case Pi:
+		t.domain.typ = Substitute(t.domain.typ, x, s)
+		if t.defaultArg != nil {
+			t.defaultArg = Substitute(t.defaultArg, x, s)
+		}
+		if t.domain.name == x { // `x` is shadowed by the binder
+			return t
+		}
+		if t.domain.name != "" && occursFree(t.domain.name, s) && occursFree(x, t.target) {
+			// rename the binder so it doesn't capture the free variables of `s`
+			fresh := t.domain.name + "'"
+			for occursFree(fresh, s) || occursFree(fresh, t.target) {
+				fresh += "'"
+			}
+			t.target = Substitute(t.target, t.domain.name, variable(fresh))
+			t.domain.name = fresh
+		}
+		t.target = Substitute(t.target, x, s)
+		return t
+	case api.App:
+		out := make(api.App, len(t))
+		for i, elem := range t {
+			out[i] = Substitute(elem, x, s)
+		}
+		return out
+	}
+	return ty
+}
+
+// returns a human-readable representation of `ty`
+func String(ty api.Type) string {
+	switch t := ty.(type) {
+	case nil:
+		return "?"
+	case variable:
+		return string(t)
+	case Constant:
+		return t.Constant()
+	case sym:
+		return String(t.typ)
+	case Pi:
+		return t.String()
+	case api.App:
+		elems := make([]string, len(t))
+		for i, elem := range t {
+			elems[i] = String(elem)
+			if _, isApp := elem.(api.App); isApp && i > 0 || isPi(elem) {
+				elems[i] = "(" + elems[i] + ")"
+			}
+		}
+		return strings.Join(elems, " ")
+	}
+	head, _ := ty.Break()
+	return head
+}
+
+func isPi(ty api.Type) bool {
+	_, ok := ty.(Pi)
+	return ok
+}
+
+var multiplicityStrings = map[int8]string{Erase: "erase ", Once: "once ", Unlimited: ""}
+
+func (p Pi) String() string {
+	domain := String(p.domain.typ)
+	if p.constraint {
+		return domain + " => " + String(p.target)
+	}
+	mult := multiplicityStrings[p.domain.multiplicity]
+	if p.implicit && p.defaultArg != nil {
+		domain = "{" + mult + p.domain.name + " : " + domain + " := " + String(p.defaultArg) + "}"
+	} else if p.implicit {
+		domain = "{" + mult + p.domain.name + " : " + domain + "}"
+	} else if p.domain.name != "" {
+		domain = "(" + mult + p.domain.name + " : " + domain + ")"
+	} else if isPi(p.domain.typ) {
+		domain = "(" + domain + ")"
+	}
+	return domain + " -> " + String(p.target)
+}
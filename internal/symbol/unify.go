@@ -0,0 +1,208 @@
+package symbol
+
+import (
+	"fmt"
+
+	"github.com/petersalex27/yew/api"
+)
+
+// a failure to unify two types
+type Mismatch struct {
+	// expected type, with all solved free variables substituted
+	Expected api.Type
+	// actual type, with all solved free variables substituted
+	Actual api.Type
+	// true iff unification failed because solving a free variable would create an infinite type
+	Infinite bool
+}
+
+func (m Mismatch) Error() string {
+	if m.Infinite {
+		return fmt.Sprintf("cannot construct infinite type `%s ~ %s`", String(m.Expected), String(m.Actual))
+	}
+	return fmt.Sprintf("cannot unify `%s` with `%s`", String(m.Expected), String(m.Actual))
+}
+
+// solves free variables (e.g., `~a`, `~a1`, ...) by unification
+//
+// only free variables are solved; all other variables are rigid and only unify with themselves
+type Unifier struct {
+	// maps free variables to their solutions
+	substitution map[string]api.Type
+}
+
+func NewUnifier() *Unifier {
+	return &Unifier{substitution: make(map[string]api.Type)}
+}
+
+// returns the solution of the free variable `x` if it's been solved
+func (u *Unifier) Solution(x string) (ty api.Type, solved bool) {
+	ty, solved = u.substitution[x]
+	return ty, solved
+}
+
+// follows the substitution until `ty` is no longer a solved free variable
+func (u *Unifier) walk(ty api.Type) api.Type {
+	for {
+		if s, isSym := ty.(sym); isSym {
+			ty = s.typ
+		}
+		x, isVar := IsVariable(ty)
+		if !isVar {
+			return ty
+		}
+		solution, solved := u.substitution[x]
+		if !solved {
+			return ty
+		}
+		ty = solution
+	}
+}
+
+// returns `ty` with every solved free variable replaced by its solution
+func (u *Unifier) Apply(ty api.Type) api.Type {
+	switch t := u.walk(ty).(type) {
+	case Pi:
+		t.domain.typ = u.Apply(t.domain.typ)
+		t.target = u.Apply(t.target)
+		if t.defaultArg != nil {
+			t.defaultArg = u.Apply(t.defaultArg)
+		}
+		return t
+	case api.App:
+		out := make(api.App, len(t))
+		for i, elem := range t {
+			out[i] = u.Apply(elem)
+		}
+		return out
+	default:
+		return t
+	}
+}
+
+// returns true iff the free variable `x` occurs in `ty`
+func (u *Unifier) occurs(x string, ty api.Type) bool {
+	ty = u.walk(ty)
+	if y, isVar := IsVariable(ty); isVar {
+		return x == y
+	} else if pi, isPi := ty.(Pi); isPi {
+		return u.occurs(x, pi.domain.typ) || u.occurs(x, pi.target)
+	}
+
+	_, tail := ty.Break()
+	for _, elem := range tail {
+		if u.occurs(x, elem) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *Unifier) mismatch(expected, actual api.Type, infinite bool) error {
+	return Mismatch{Expected: u.Apply(expected), Actual: u.Apply(actual), Infinite: infinite}
+}
+
+// solves the free variable `x` with `ty`
+func (u *Unifier) solve(x string, ty api.Type, expected, actual api.Type) error {
+	if y, isVar := IsVariable(ty); isVar && x == y {
+		return nil
+	} else if u.occurs(x, ty) {
+		return u.mismatch(expected, actual, true)
+	}
+	u.substitution[x] = ty
+	return nil
+}
+
+// unifies `expected` and `actual`, solving free variables as needed
+//
+// returns a `Mismatch` if the types cannot be unified; solutions found before the mismatch are kept
+func (u *Unifier) Unify(expected, actual api.Type) error {
+	a, b := u.walk(expected), u.walk(actual)
+	if IsFree(a) {
+		x, _ := IsVariable(a)
+		return u.solve(x, b, expected, actual)
+	} else if IsFree(b) {
+		y, _ := IsVariable(b)
+		return u.solve(y, a, expected, actual)
+	}
+
+	// rigid variables only unify with themselves
+	x, isVarA := IsVariable(a)
+	y, isVarB := IsVariable(b)
+	if isVarA || isVarB {
+		if isVarA && isVarB && x == y {
+			return nil
+		}
+		return u.mismatch(expected, actual, false)
+	}
+
+	if piA, isPi := a.(Pi); isPi {
+		if piB, isPi := b.(Pi); isPi {
+			return u.unifyPi(piA, piB, expected, actual)
+		}
+		return u.mismatch(expected, actual, false)
+	}
+
+	if appA, isApp := a.(api.App); isApp {
+		if appB, isApp := b.(api.App); isApp && len(appA) == len(appB) {
+			return u.unifyEach(appA, appB)
+		}
+	}
+
+	return u.unifyBroken(a, b, expected, actual)
+}
+
+// unifies two pi types, which must agree on how their arguments are passed--implicitly or not, as
+// constraints or not--and on their multiplicity, e.g., `{x : A} -> B` doesn't unify with `A -> B`
+func (u *Unifier) unifyPi(a, b Pi, expected, actual api.Type) error {
+	if a.implicit != b.implicit || a.constraint != b.constraint || a.domain.multiplicity != b.domain.multiplicity {
+		return u.mismatch(expected, actual, false)
+	}
+	if err := u.Unify(a.domain.typ, b.domain.typ); err != nil {
+		return err
+	}
+
+	// rename `b`'s binder to `a`'s binder so both targets refer to the same variable
+	targetB := b.target
+	if b.domain.name != "" && b.domain.name != a.domain.name {
+		binder := a.domain.name
+		if binder == "" {
+			binder = "_" + b.domain.name
+		}
+		targetB = Substitute(targetB, b.domain.name, variable(binder))
+	}
+	return u.Unify(a.target, targetB)
+}
+
+// unifies two types by comparing their constants, heads, and tails
+func (u *Unifier) unifyBroken(a, b api.Type, expected, actual api.Type) error {
+	if a.Constant() != b.Constant() {
+		return u.mismatch(expected, actual, false)
+	}
+
+	headA, tailA := a.Break()
+	headB, tailB := b.Break()
+	if len(tailA) != len(tailB) {
+		return u.mismatch(expected, actual, false)
+	} else if len(tailA) == 0 {
+		if headA != headB {
+			return u.mismatch(expected, actual, false)
+		}
+		return nil
+	}
+
+	if headA != headB {
+		return u.mismatch(expected, actual, false)
+	}
+	return u.unifyEach(tailA, tailB)
+}
+
+// unifies each pair of types in `as` and `bs`, which must have the same length
+func (u *Unifier) unifyEach(as, bs []api.Type) error {
+	for i := range as {
+		if err := u.Unify(as[i], bs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
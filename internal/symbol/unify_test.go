@@ -0,0 +1,105 @@
+package symbol
+
+import (
+	"testing"
+
+	"github.com/petersalex27/yew/api"
+)
+
+func TestUnify(t *testing.T) {
+	nat := MakeConstant("", "Nat")
+	list := MakeConstant("", "List")
+	a, b := variable("~a"), variable("~b")
+
+	tests := []struct {
+		name     string
+		expected api.Type
+		actual   api.Type
+		ok       bool
+		infinite bool
+	}{
+		{"constants", nat, nat, true, false},
+		{"different constants", nat, list, false, false},
+		{"free variable", a, nat, true, false},
+		{"rigid variable", variable("x"), variable("x"), true, false},
+		{"different rigid variables", variable("x"), variable("y"), false, false},
+		{"rigid against constant", variable("x"), nat, false, false},
+		{"application", api.App{list, a}, api.App{list, nat}, true, false},
+		{"application arity", api.App{list, a}, api.App{list, nat, nat}, false, false},
+		{"pi", MakePi("", Unlimited, false, a, b), MakePi("", Unlimited, false, nat, nat), true, false},
+		{"pi implicitness", MakePi("", Unlimited, true, a, b), MakePi("", Unlimited, false, nat, nat), false, false},
+		{"pi multiplicity", MakePi("x", Once, false, nat, nat), MakePi("x", Unlimited, false, nat, nat), false, false},
+		{"pi erasure", MakePi("x", Erase, false, nat, nat), MakePi("x", Unlimited, false, nat, nat), false, false},
+		{"pi constraint", MakeConstraint(nat, nat), MakePi("", Unlimited, true, nat, nat), false, false},
+		{"pi binders", MakePi("x", Unlimited, false, nat, variable("x")), MakePi("y", Unlimited, false, nat, variable("y")), true, false},
+		{"occurs", a, api.App{list, a}, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := NewUnifier().Unify(test.expected, test.actual)
+			if (err == nil) != test.ok {
+				t.Fatalf("Expected success=%t, got error %v", test.ok, err)
+			} else if err == nil {
+				return
+			}
+
+			mismatch, isMismatch := err.(Mismatch)
+			if !isMismatch {
+				t.Fatalf("Expected a Mismatch, got %T", err)
+			} else if mismatch.Infinite != test.infinite {
+				t.Errorf("Expected Infinite=%t, got %t", test.infinite, mismatch.Infinite)
+			}
+		})
+	}
+}
+
+func TestUnifySolutions(t *testing.T) {
+	nat := MakeConstant("", "Nat")
+	list := MakeConstant("", "List")
+	a, b := variable("~a"), variable("~b")
+
+	u := NewUnifier()
+	if err := u.Unify(api.App{list, a}, b); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := u.Unify(b, api.App{list, nat}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if actual := String(u.Apply(a)); actual != "Nat" {
+		t.Errorf("Expected ~a=Nat, got %s", actual)
+	}
+	if actual := String(u.Apply(MakePi("", Unlimited, false, a, b))); actual != "Nat -> List Nat" {
+		t.Errorf("Expected `Nat -> List Nat`, got `%s`", actual)
+	}
+
+	// solutions are kept, so `~a` can no longer unify with anything but `Nat`
+	if err := u.Unify(a, list); err == nil {
+		t.Errorf("Expected a mismatch for `Nat` and `List`")
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	nat := MakeConstant("", "Nat")
+	a, b := variable("a"), variable("b")
+	tests := []struct {
+		name     string
+		ty       api.Type
+		x        string
+		s        api.Type
+		expected string
+	}{
+		{"free", MakePi("", Unlimited, false, a, a), "a", nat, "Nat -> Nat"},
+		{"shadowed", MakePi("a", Unlimited, false, nat, a), "a", nat, "(a : Nat) -> a"},
+		{"capture", MakePi("b", Unlimited, false, nat, api.App{a, b}), "a", b, "(b' : Nat) -> b b'"},
+		{"capture renamed", MakePi("b", Unlimited, false, nat, api.App{a, b, variable("b'")}), "a", b, "(b'' : Nat) -> b b'' b'"},
+		{"no capture", MakePi("b", Unlimited, false, nat, b), "a", b, "(b : Nat) -> b"},
+	}
+
+	for _, test := range tests {
+		if actual := String(Substitute(test.ty, test.x, test.s)); actual != test.expected {
+			t.Errorf("%s: expected `%s`, got `%s`", test.name, test.expected, actual)
+		}
+	}
+}